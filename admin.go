@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+func init() {
+	registerCommand(command {
+		name:  "grants",
+		usage: "[user[@host]]",
+		help:  "List users and their privileges",
+		run:   grantsCommand,
+	})
+
+	registerCommand(command {
+		name: "processlist",
+		help: "Show running connections, refreshing every few seconds",
+		run:  processlistCommand,
+	})
+
+	registerCommand(command {
+		name:  "kill",
+		usage: "<id> [query]",
+		help:  "Kill a connection, or only its running query",
+		run:   killCommand,
+	})
+
+	registerCommand(command {
+		name: "locks",
+		help: "Show lock waits and who is blocking them, refreshing",
+		run:  locksCommand,
+	})
+
+	registerCommand(command {
+		name: "deadlock",
+		help: "Show the latest detected deadlock",
+		run:  deadlockCommand,
+	})
+}
+
+var grantPattern = regexp.MustCompile(
+	`^GRANT (.+?)(?: ON (.+?))? TO (\S+)( WITH (?:GRANT|ADMIN) OPTION)?$`)
+
+// Splits a SHOW GRANTS line into privileges, object and whether it can be
+// granted on. Role grants have no object.
+func parseGrant(grant string) (string, string, string) {
+	match := grantPattern.FindStringSubmatch(grant)
+	if match == nil {
+		return grant, "", ""
+	}
+
+	object := match[2]
+	if object == "" {
+		object = "(role)"
+	}
+
+	grantable := "no"
+	if match[4] != "" {
+		grantable = "yes"
+	}
+
+	return match[1], object, grantable
+}
+
+func grantsCommand(args []string) error {
+	query := "SELECT user, host FROM mysql.user"
+	queryArgs := []interface{} {}
+
+	switch len(args) {
+	case 0:
+	case 1:
+		user := strings.SplitN(args[0], "@", 2)
+		query += " WHERE user = ?"
+		queryArgs = append(queryArgs, user[0])
+
+		if len(user) == 2 {
+			query += " AND host = ?"
+			queryArgs = append(queryArgs, user[1])
+		}
+	default:
+		return usageError("grants")
+	}
+
+	users, err := queryStrings(query + " ORDER BY user, host",
+				   queryArgs...)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string {}
+
+	for _, user := range users {
+		grants, err := queryStrings(fmt.Sprintf("SHOW GRANTS FOR %s@%s",
+			quoteString(user[0]), quoteString(user[1])))
+		if err != nil {
+			return err
+		}
+
+		for _, grant := range grants {
+			privileges, object, grantable := parseGrant(grant[0])
+
+			rows = append(rows, []string {user[0], user[1],
+				privileges, object, grantable})
+		}
+	}
+
+	showResults([]string {"user", "host", "privileges", "on",
+			      "grantable"}, rows)
+	status.Text = trf("%d users", len(users))
+	return nil
+}
+
+func processlistCommand(args []string) error {
+	return startLiveView("processlist", func() (resultSet, error) {
+		return fetchResultSet("SHOW FULL PROCESSLIST")
+	})
+}
+
+func kill(id string, queryOnly bool) error {
+	statement := "KILL CONNECTION "
+	if queryOnly {
+		statement = "KILL QUERY "
+	}
+
+	// KILL doesn't accept placeholders.
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return errors.New(trf("Invalid connection id '%s'", id))
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	_, err := execStatement(ctx, db, statement + id)
+	return err
+}
+
+func confirmKill(id string, queryOnly bool) {
+	question := trf("Kill connection %s?", id)
+	if queryOnly {
+		question = trf("Kill the query running on connection %s?", id)
+	}
+
+	confirm(question, func() {
+		if err := kill(id, queryOnly); err != nil {
+			showError(err.Error())
+			return
+		}
+
+		showMessage(trf("Killed %s", id))
+	})
+}
+
+func killCommand(args []string) error {
+	switch {
+	case len(args) == 1:
+		confirmKill(args[0], false)
+	case len(args) == 2 && args[1] == "query":
+		confirmKill(args[0], true)
+	default:
+		return usageError("kill")
+	}
+
+	return nil
+}
+
+// The live views that can kill the selected row's connection, and the
+// column holding its id.
+var killColumns = map[string]string {
+	"processlist": "Id",
+	"locks":       "blocking_pid",
+}
+
+// In views listing connections, x kills the selected row's query and X its
+// whole connection.
+func handleKillEvent(ev escapebox.Event) bool {
+	if live == nil || killColumns[live.name] == "" ||
+	   ev.Type != termbox.EventKey || ev.Ch != 'x' && ev.Ch != 'X' {
+		return false
+	}
+
+	if results.SelectedRow >= len(results.Rows) {
+		return true
+	}
+
+	column := -1
+	for i := range results.Columns {
+		if strings.EqualFold(columnName(i), killColumns[live.name]) {
+			column = i
+		}
+	}
+
+	if column < 0 {
+		return true
+	}
+
+	confirmKill(cellValue(results.SelectedRow, column), ev.Ch == 'x')
+	return true
+}
+
+func locksCommand(args []string) error {
+	return startLiveView("locks", func() (resultSet, error) {
+		set, err := fetchResultSet("SELECT waiting_pid, wait_age, " +
+			"locked_table, locked_index, locked_type, " +
+			"waiting_query, blocking_pid, blocking_query " +
+			"FROM sys.innodb_lock_waits ORDER BY wait_started")
+		if err != nil {
+			return set, errors.New(
+				trf("Reading sys.innodb_lock_waits: %s", err))
+		}
+
+		return set, nil
+	})
+}
+
+func deadlockCommand(args []string) error {
+	return innodbCommand([]string {"deadlock"})
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"fmt"
+	"errors"
+	"strings"
+	"database/sql"
+)
+
+// MySQL's limit on identifier length.
+const maxIndexName int = 64
+
+type tableRef struct {
+	database string
+	table    string
+}
+
+type columnRef struct {
+	qualifier string
+	name      string
+}
+
+// A table EXPLAIN says is read in full or sorted after reading.
+type planProblem struct {
+	table    string
+	fullScan bool
+	filesort bool
+}
+
+// The tables a statement reads and the columns it filters and sorts on, as
+// far as can be told without the server parsing it.
+type queryColumns struct {
+	// Where tables without a database are, the editor's current one.
+	database string
+
+	tables map[string]tableRef
+	equal  []columnRef
+	ranged []columnRef
+	sorted []columnRef
+}
+
+func init() {
+	registerCommand(command {
+		name: "advise",
+		help: "Suggest indexes for the statement under the cursor",
+		run:  adviseCommand,
+	})
+}
+
+// Reads the traditional EXPLAIN table; other formats have none of these
+// columns and so never report problems.
+func planProblems(columns []string, rows [][]string) []planProblem {
+	index := map[string]int {}
+	for i, column := range columns {
+		index[strings.ToLower(column)] = i
+	}
+
+	tableColumn, ok1 := index["table"]
+	typeColumn, ok2 := index["type"]
+	extraColumn, ok3 := index["extra"]
+	if !ok1 || !ok2 || !ok3 {
+		return nil
+	}
+
+	problems := []planProblem {}
+
+	for _, row := range rows {
+		table := row[tableColumn]
+
+		// Derived tables and unions are judged by their own rows.
+		if table == "null" || strings.HasPrefix(table, "<") {
+			continue
+		}
+
+		problem := planProblem {
+			table:    table,
+			fullScan: row[typeColumn] == "ALL",
+			filesort: strings.Contains(row[extraColumn], "filesort"),
+		}
+
+		if problem.fullScan || problem.filesort {
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems
+}
+
+func isName(t token) bool {
+	return t.kind == tokenWord || t.kind == tokenIdentifier
+}
+
+func nameText(text []rune, t token) string {
+	name := string(text[t.start:t.end])
+
+	if t.kind == tokenIdentifier && len(name) >= 2 {
+		quote := name[:1]
+		name = name[1:len(name) - 1]
+		name = strings.Replace(name, quote + quote, quote, -1)
+	}
+
+	return name
+}
+
+func isKeyword(text []rune, t token) bool {
+	return t.kind == tokenWord &&
+	       dialects[sqlDialect].keywords.contains(nameText(text, t))
+}
+
+// Reads a column name, possibly qualified with a table, at words[i].
+// Returns the index just past it.
+func readColumn(text []rune, words []token, i int) (columnRef, int) {
+	ref := columnRef {
+		name: nameText(text, words[i]),
+	}
+
+	for i + 2 < len(words) && isSymbol(text, words[i + 1], '.') &&
+	    isName(words[i + 2]) {
+		ref.qualifier = ref.name
+		ref.name = nameText(text, words[i + 2])
+		i += 2
+	}
+
+	return ref, i + 1
+}
+
+// Reads a table reference with an optional alias starting at words[i], and
+// returns the index just past it.
+func (q *queryColumns) readTable(text []rune, words []token, i int) int {
+	if i >= len(words) || !isName(words[i]) || isKeyword(text, words[i]) {
+		return i
+	}
+
+	ref := tableRef {
+		database: q.database,
+		table:    nameText(text, words[i]),
+	}
+	i++
+
+	if i + 1 < len(words) && isSymbol(text, words[i], '.') &&
+	   isName(words[i + 1]) {
+		ref.database = ref.table
+		ref.table = nameText(text, words[i + 1])
+		i += 2
+	}
+
+	q.tables[strings.ToLower(ref.table)] = ref
+
+	if i + 1 < len(words) && isWord(text, words[i], "AS") {
+		i++
+	}
+
+	if i < len(words) && isName(words[i]) && !isKeyword(text, words[i]) {
+		q.tables[strings.ToLower(nameText(text, words[i]))] = ref
+		i++
+	}
+
+	return i
+}
+
+func isComparison(text []rune, t token) bool {
+	return isSymbol(text, t, '<') || isSymbol(text, t, '>') ||
+	       isSymbol(text, t, '!') || isWord(text, t, "BETWEEN") ||
+	       isWord(text, t, "LIKE")
+}
+
+func isEquality(text []rune, t token) bool {
+	return isSymbol(text, t, '=') || isWord(text, t, "IN") ||
+	       isWord(text, t, "IS")
+}
+
+func findQueryColumns(query, database string) queryColumns {
+	text := []rune(query)
+	q := queryColumns {
+		database: database,
+		tables:   map[string]tableRef {},
+	}
+
+	words := []token {}
+	for _, t := range lex(text, sqlDialect) {
+		if t.kind != tokenWhitespace && t.kind != tokenComment {
+			words = append(words, t)
+		}
+	}
+
+	// The clause being read at each level of parentheses.
+	clauses := []string {""}
+
+	for i := 0; i < len(words); i++ {
+		t := words[i]
+		clause := &clauses[len(clauses) - 1]
+
+		switch {
+		case isSymbol(text, t, '('):
+			clauses = append(clauses, *clause)
+			continue
+
+		case isSymbol(text, t, ')'):
+			if len(clauses) > 1 {
+				clauses = clauses[:len(clauses) - 1]
+			}
+			continue
+
+		case isSymbol(text, t, ',') && *clause == "FROM":
+			i = q.readTable(text, words, i + 1) - 1
+			continue
+
+		case !isName(t):
+			continue
+		}
+
+		word := strings.ToUpper(nameText(text, t))
+
+		if t.kind == tokenWord {
+			switch word {
+			case "FROM", "JOIN", "UPDATE":
+				*clause = "FROM"
+				i = q.readTable(text, words, i + 1) - 1
+				continue
+			case "SELECT", "WHERE", "ON", "GROUP", "HAVING", "ORDER",
+			     "LIMIT", "SET", "USING", "VALUES", "UNION":
+				*clause = word
+				continue
+			}
+		}
+
+		ref, next := readColumn(text, words, i)
+		function := next < len(words) && isSymbol(text, words[next], '(')
+		keyword := ref.qualifier == "" && isKeyword(text, t)
+		after := token {}
+		if next < len(words) {
+			after = words[next]
+		}
+
+		// The other side of a comparison, as in a join condition.
+		compared := !keyword && i > 0 &&
+			    isSymbol(text, words[i - 1], '=')
+
+		switch {
+		case function:
+
+		case *clause == "WHERE" || *clause == "ON":
+			if isEquality(text, after) || compared {
+				q.equal = append(q.equal, ref)
+			} else if isComparison(text, after) {
+				q.ranged = append(q.ranged, ref)
+			}
+
+		case *clause == "ORDER" && !keyword:
+			q.sorted = append(q.sorted, ref)
+		}
+
+		i = next - 1
+	}
+
+	return q
+}
+
+// Loads the names of a table's columns, to tell which table an unqualified
+// column belongs to.
+func loadColumnNames(ref tableRef) (map[string]bool, error) {
+	rows, err := queryStrings("SELECT column_name " +
+				  "FROM information_schema.columns " +
+				  "WHERE table_schema = ? AND table_name = ?",
+				  ref.database, ref.table)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool {}
+	for _, row := range rows {
+		names[strings.ToLower(row[0])] = true
+	}
+
+	return names, nil
+}
+
+// Columns for an index on one table: those compared for equality first,
+// then the sort order (when the rows are being sorted) and finally one
+// range, after which MySQL can't use any more of the index.
+func (q *queryColumns) indexColumns(ref tableRef, names map[string]bool,
+				    filesort bool) []string {
+	belongs := func(c columnRef) bool {
+		if c.qualifier == "" {
+			return names[strings.ToLower(c.name)]
+		}
+
+		return q.tables[strings.ToLower(c.qualifier)] == ref
+	}
+
+	columns := []string {}
+	seen := map[string]bool {}
+
+	add := func(refs []columnRef, limit int) {
+		for _, c := range refs {
+			key := strings.ToLower(c.name)
+			if !belongs(c) || seen[key] || limit == 0 {
+				continue
+			}
+
+			seen[key] = true
+			columns = append(columns, c.name)
+			limit--
+		}
+	}
+
+	add(q.equal, -1)
+	if filesort {
+		add(q.sorted, -1)
+	}
+	add(q.ranged, 1)
+
+	return columns
+}
+
+func indexName(table string, columns []string) string {
+	name := "idx_" + table + "_" + strings.Join(columns, "_")
+
+	if len(name) > maxIndexName {
+		name = name[:maxIndexName]
+	}
+
+	return name
+}
+
+func describeProblem(p planProblem) string {
+	switch {
+	case p.fullScan && p.filesort:
+		return tr("full table scan and filesort")
+	case p.fullScan:
+		return tr("full table scan")
+	}
+
+	return tr("filesort")
+}
+
+// Turns the problems EXPLAIN found into CREATE INDEX statements, with a
+// comment saying why each one is suggested.
+func adviseIndexes(query, database string,
+		   problems []planProblem) (string, error) {
+	q := findQueryColumns(query, database)
+	lines := []string {}
+
+	for _, p := range problems {
+		lines = append(lines, fmt.Sprintf("-- %s: %s", p.table,
+						   describeProblem(p)))
+
+		ref, ok := q.tables[strings.ToLower(p.table)]
+		if !ok {
+			lines = append(lines, tr("-- (table not found in the " +
+						 "statement)"), "")
+			continue
+		}
+
+		names, err := loadColumnNames(ref)
+		if err != nil {
+			return "", err
+		}
+
+		columns := q.indexColumns(ref, names, p.filesort)
+		if len(columns) == 0 {
+			lines = append(lines, tr("-- (no filtered or sorted " +
+						 "columns to index)"), "")
+			continue
+		}
+
+		quoted := []string {}
+		for _, column := range columns {
+			quoted = append(quoted, quoteIdentifier(column))
+		}
+
+		lines = append(lines, fmt.Sprintf("CREATE INDEX %s ON %s (%s);",
+			quoteIdentifier(indexName(ref.table, columns)),
+			qualifiedTable(ref.database, ref.table),
+			strings.Join(quoted, ", ")), "")
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// Removes EXPLAIN and its options from the start of a statement, so the
+// statement under the cursor can be advised on either way.
+func stripExplain(query string) string {
+	text := []rune(query)
+	words := []token {}
+	for _, t := range lex(text, sqlDialect) {
+		if t.kind != tokenWhitespace && t.kind != tokenComment {
+			words = append(words, t)
+		}
+	}
+
+	if len(words) == 0 || !isWord(text, words[0], "EXPLAIN") {
+		return query
+	}
+
+	i := 1
+	for i < len(words) {
+		switch {
+		case isWord(text, words[i], "EXTENDED"),
+		     isWord(text, words[i], "PARTITIONS"):
+			i++
+		case isWord(text, words[i], "FORMAT") && i + 2 < len(words):
+			i += 3
+		default:
+			return string(text[words[i].start:])
+		}
+	}
+
+	return ""
+}
+
+// After an EXPLAIN run from the editor, points out that advise has
+// something to say about it.
+func noteExplain(query string, columns []string, rows [][]string) {
+	if sqlDialect != dialectMySQL || leadingWords(query)[0] != "EXPLAIN" {
+		return
+	}
+
+	if problems := planProblems(columns, rows); len(problems) > 0 {
+		status.Text = trf("%d tables scanned in full or filesorted " +
+				  "(run advise for index suggestions)",
+				  len(problems))
+	}
+}
+
+func adviseCommand(args []string) error {
+	if len(args) != 0 {
+		return usageError("advise")
+	}
+
+	if sqlDialect != dialectMySQL {
+		return errors.New(tr("The index advisor only reads MySQL's " +
+				     "EXPLAIN output"))
+	}
+
+	query := stripExplain(currentQuery())
+	if query == "" {
+		return errors.New(tr("There is no statement under the cursor"))
+	}
+
+	// On the editor's connection, where a USE there has changed what
+	// unqualified table names mean.
+	conn, err := editorConnection()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	// NULL when no database is selected.
+	var database sql.NullString
+	err = conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database)
+	if err != nil {
+		return err
+	}
+
+	res, err := conn.QueryContext(ctx, "EXPLAIN " + query)
+	if err != nil {
+		return err
+	}
+
+	columns, rows, err := scanStrings(res)
+	res.Close()
+	if err != nil {
+		return err
+	}
+
+	problems := planProblems(columns, rows)
+	if len(problems) == 0 {
+		showMessage(tr("EXPLAIN shows no full table scans or filesorts"))
+		return nil
+	}
+
+	text, err := adviseIndexes(query, database.String, problems)
+	if err != nil {
+		return err
+	}
+
+	showPopup(tr("Index suggestions"), text, true)
+	return nil
+}
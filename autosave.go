@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+	"io/ioutil"
+	"path/filepath"
+)
+
+const autosaveDelay time.Duration = 500 * time.Millisecond
+
+const mainBuffer string = "main"
+
+// Writes buffer contents to disk in the background, at most once per
+// autosaveDelay no matter how fast the text changes.
+type autosaver struct {
+	path    string
+	mutex   sync.Mutex
+	timer   *time.Timer
+	text    string
+	dirty   bool
+	err     error
+
+	// Held for the whole write, so saves of a big buffer don't overlap
+	// and an older text can't land after a newer one.
+	writing sync.Mutex
+}
+
+var autosave autosaver
+
+func autosavePath(conn Connection, buffer string) string {
+	return filepath.Join(configDir(), "autosave", conn.slug(),
+			     buffer + ".sql")
+}
+
+func newAutosaver(path string) autosaver {
+	return autosaver {
+		path: path,
+	}
+}
+
+func (a *autosaver) schedule(text string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.text = text
+	a.dirty = true
+
+	if a.timer == nil {
+		a.timer = time.AfterFunc(autosaveDelay, a.flush)
+	} else {
+		a.timer.Reset(autosaveDelay)
+	}
+}
+
+// The file is written without holding mutex, so typing never waits for a
+// large buffer to reach the disk.
+func (a *autosaver) flush() {
+	a.writing.Lock()
+	defer a.writing.Unlock()
+
+	a.mutex.Lock()
+	if !a.dirty {
+		a.mutex.Unlock()
+		return
+	}
+
+	path, text := a.path, a.text
+	a.dirty = false
+	a.mutex.Unlock()
+
+	err := writeFileAtomic(path, []byte(text))
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.err = err
+	if err != nil {
+		a.dirty = true
+	}
+}
+
+// Returns and clears the error from the last failed save, if any.
+func (a *autosaver) lastError() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	err := a.err
+	a.err = nil
+	return err
+}
+
+func (a *autosaver) load() (string, error) {
+	text, err := ioutil.ReadFile(a.path)
+	return string(text), err
+}
+
+// Writes to a temp file first so a crash mid-write can't leave a truncated
+// file behind.
+func writeFileAtomic(path string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	temp := path + ".tmp"
+
+	err = ioutil.WriteFile(temp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"os"
+	"fmt"
+	"errors"
+	"flag"
+	"strings"
+)
+
+var batchQuery  string
+var batchFile   string
+var batchFormat string
+
+func init() {
+	flag.StringVar(&batchQuery, "e", "",
+		       "Run these statements, print the results and exit")
+	flag.StringVar(&batchFile, "f", "",
+		       "Run the statements in this file, print the results " +
+		       "and exit")
+	flag.StringVar(&batchFormat, "format", "table",
+		       "Output format for -e, -f and piped SQL: table, csv, " +
+		       "json, ndjson or md")
+}
+
+// Whether SQL is being piped in rather than typed at a terminal.
+func stdinPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode() & os.ModeCharDevice == 0
+}
+
+func batchMode() bool {
+	return batchQuery != "" || batchFile != "" || stdinPiped()
+}
+
+// Runs each statement in order on one connection, printing the results of
+// those that return rows. Stops at the first error.
+func runBatch() error {
+	write, ok := outputFormats[batchFormat]
+	if !ok {
+		return errors.New(trf("Unknown output format '%s'",
+				      batchFormat))
+	}
+
+	var input io.Reader = os.Stdin
+
+	if batchQuery != "" {
+		input = strings.NewReader(batchQuery)
+	}
+
+	if batchFile != "" {
+		file, err := os.Open(batchFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		input = file
+	}
+
+	script := newScriptReader(input)
+	defer runningHooks.Wait()
+
+	conn, err := db.Conn(appContext)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		query, err := script.next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := queryContext()
+		res, err := queryStatement(ctx, conn, query)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("%s: %s", abbreviate(query), err)
+		}
+
+		columns, rows, err := scanNullStrings(res)
+		res.Close()
+		cancel()
+
+		if err != nil {
+			return err
+		}
+
+		if len(columns) == 0 {
+			continue
+		}
+
+		if err := write(os.Stdout, columns, rows); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+const binlogPageSize int = 200
+
+// How many pages of events one step reads looking for ones that match the
+// filter, so a rare match doesn't read the whole log at once.
+const binlogScanPages int = 50
+
+// The binlog page being shown, so n and p can move through the log.
+type binlogPosition struct {
+	file   string
+	from   int
+	offset int
+	filter string
+
+	// Where the next page starts. With a filter, that can be several
+	// pages of events on.
+	next int
+
+	// Where the pages before this one started, for p.
+	back []int
+}
+
+var binlog binlogPosition
+
+func init() {
+	registerCommand(command {
+		name: "binlogs",
+		help: "List the server's binary logs",
+		run:  binlogsCommand,
+	})
+
+	registerCommand(command {
+		name:  "binlog",
+		usage: "[file] [position] [text]",
+		help:  "Browse binary log events from a position, matching text",
+		run:   binlogCommand,
+	})
+}
+
+func binlogsCommand(args []string) error {
+	columns, rows, err := fetchStrings("SHOW BINARY LOGS")
+	if err != nil {
+		return err
+	}
+
+	showResults(columns, rows)
+	status.Text = trf("%d binary logs", len(rows))
+	return nil
+}
+
+func binlogQuery(position binlogPosition, offset int) string {
+	query := "SHOW BINLOG EVENTS"
+
+	if position.file != "" {
+		query += " IN " + quoteString(position.file)
+	}
+
+	if position.from > 0 {
+		query += fmt.Sprintf(" FROM %d", position.from)
+	}
+
+	return query + fmt.Sprintf(" LIMIT %d, %d", offset, binlogPageSize)
+}
+
+func binlogMatches(row []string, filter string) bool {
+	return strings.Contains(strings.ToLower(strings.Join(row, " ")),
+				strings.ToLower(filter))
+}
+
+// Shows the events from position.offset on. With a filter, pages are read
+// until there's a page of matching events or the log runs out.
+func showBinlogPage(position binlogPosition) error {
+	columns := []string {}
+	rows := [][]string {}
+	count := 0
+
+	for page := 0; page < binlogScanPages; page++ {
+		query := binlogQuery(position, position.offset + count)
+
+		pageColumns, events, err := fetchStrings(query)
+		if err != nil {
+			return err
+		}
+
+		columns = pageColumns
+		count += len(events)
+
+		for _, row := range events {
+			if binlogMatches(row, position.filter) {
+				rows = append(rows, row)
+			}
+		}
+
+		if position.filter == "" || len(events) < binlogPageSize ||
+		   len(rows) >= binlogPageSize {
+			break
+		}
+	}
+
+	// Moving past the last event leaves the previous page showing.
+	if count == 0 && position.offset > 0 {
+		status.Text = tr("No more events")
+		return nil
+	}
+
+	position.next = position.offset + count
+
+	showResults(columns, rows)
+	resultsView = "binlog"
+	binlog = position
+
+	status.Text = trf("Events %d-%d, %d shown  " +
+			  "(n: next page, p: previous page)",
+			  position.offset + 1, position.offset + count,
+			  len(rows))
+	return nil
+}
+
+func binlogCommand(args []string) error {
+	position := binlogPosition {}
+
+	if len(args) > 0 {
+		position.file = args[0]
+	}
+
+	if len(args) > 1 {
+		from, err := strconv.Atoi(args[1])
+		if err != nil {
+			return usageError("binlog")
+		}
+
+		position.from = from
+	}
+
+	if len(args) > 2 {
+		position.filter = strings.Join(args[2:], " ")
+	}
+
+	return showBinlogPage(position)
+}
+
+func handleBinlogEvent(ev escapebox.Event) bool {
+	if resultsView != "binlog" || ev.Type != termbox.EventKey ||
+	   ev.Ch != 'n' && ev.Ch != 'p' {
+		return false
+	}
+
+	position := binlog
+	position.back = append([]int {}, binlog.back...)
+
+	if ev.Ch == 'n' {
+		position.back = append(position.back, position.offset)
+		position.offset = position.next
+	} else if len(position.back) > 0 {
+		last := len(position.back) - 1
+		position.offset = position.back[last]
+		position.back = position.back[:last]
+	} else {
+		return true
+	}
+
+	if err := showBinlogPage(position); err != nil {
+		showError(err.Error())
+	}
+
+	return true
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+type bookmarkAction int
+
+const (
+	bookmarkNone bookmarkAction = iota
+	bookmarkSet
+	bookmarkJump
+)
+
+// Bookmarked line numbers by bookmark number (1-9).
+var bookmarks = map[rune]int {}
+var pendingBookmark bookmarkAction
+
+func lineAt(text []rune, offset int) int {
+	line := 0
+
+	for i := 0; i < offset && i < len(text); i++ {
+		if text[i] == '\n' {
+			line++
+		}
+	}
+
+	return line
+}
+
+func lineStart(text []rune, line int) int {
+	for i := 0; i < len(text); i++ {
+		if line == 0 {
+			return i
+		}
+
+		if text[i] == '\n' {
+			line--
+		}
+	}
+
+	return len(text)
+}
+
+// F2 followed by a digit bookmarks the cursor's line, F3 followed by a digit
+// jumps back to it.
+func handleBookmarkEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey {
+		return false
+	}
+
+	switch {
+	case ev.Key == termbox.KeyF2:
+		pendingBookmark = bookmarkSet
+		status.Text = tr("Set bookmark: press 1-9")
+		return true
+
+	case ev.Key == termbox.KeyF3:
+		pendingBookmark = bookmarkJump
+		status.Text = tr("Jump to bookmark: press 1-9")
+		return true
+
+	case pendingBookmark == bookmarkNone:
+		return false
+	}
+
+	action := pendingBookmark
+	pendingBookmark = bookmarkNone
+	status.Text = ""
+
+	if ev.Ch < '1' || ev.Ch > '9' {
+		return true
+	}
+
+	refreshHighlight()
+
+	switch action {
+	case bookmarkSet:
+		line := lineAt(doc.text, editor.GetCursor())
+		bookmarks[ev.Ch] = line
+		showMessage(trf("Bookmark %c set on line %d", ev.Ch,
+				line + 1))
+
+	case bookmarkJump:
+		line, ok := bookmarks[ev.Ch]
+		if !ok {
+			status.Text = trf("Bookmark %c is not set", ev.Ch)
+			return true
+		}
+
+		editor.SetCursor(lineStart(doc.text, line))
+		lineHighlighter(&editor)
+	}
+
+	return true
+}
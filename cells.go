@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"encoding/hex"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+type cellPos struct {
+	row    int
+	column int
+}
+
+// tui draws one rune per cell, but termbox draws a wide character over two
+// and skips the next cell's rune, so each one in the grid is followed by
+// this zero-width space for it to skip. Without it the rest of the line
+// would lose a character for each.
+const wideFiller rune = '\u200b'
+
+// The column names as they came from the server. results.Columns has them
+// escaped like the cells, which is only for drawing them in the grid.
+var rawColumns []string
+
+// The original values of cells that had to be escaped to be drawn in the
+// grid, for the cell viewer and the pager.
+var rawCells = map[cellPos]string {}
+
+var controlEscapes = map[rune]string {
+	'\n': `\n`,
+	'\r': `\r`,
+	'\t': `\t`,
+}
+
+func needsEscape(s string) bool {
+	if !utf8.ValidString(s) {
+		return true
+	}
+
+	return strings.IndexFunc(s, unicode.IsControl) >= 0 ||
+	       strings.IndexFunc(s, isWide) >= 0
+}
+
+// Makes a value safe to draw in one grid cell: control characters and
+// bytes that aren't valid UTF-8 are written as escapes like \x1B instead of
+// being sent to the terminal, and wide characters get a wideFiller.
+func escapeValue(s string) string {
+	var escaped strings.Builder
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&escaped, `\x%02X`, s[i])
+		case controlEscapes[r] != "":
+			escaped.WriteString(controlEscapes[r])
+		case unicode.IsControl(r) && r < utf8.RuneSelf:
+			fmt.Fprintf(&escaped, `\x%02X`, r)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&escaped, `\u%04X`, r)
+		case isWide(r):
+			escaped.WriteString(s[i:i + size])
+			escaped.WriteRune(wideFiller)
+		default:
+			escaped.WriteString(s[i:i + size])
+		}
+
+		i += size
+	}
+
+	return escaped.String()
+}
+
+// Returns rows safe to draw, remembering the values that were changed.
+// Rows are only copied when they have something to escape.
+func escapeRows(rows [][]string) [][]string {
+	rawCells = map[cellPos]string {}
+	geometryCells = map[cellPos][]byte {}
+	escaped := make([][]string, len(rows))
+
+	for r, row := range rows {
+		escaped[r] = row
+		copied := false
+
+		for c, value := range row {
+			if !needsEscape(value) {
+				continue
+			}
+
+			if !copied {
+				escaped[r] = append([]string {}, row...)
+				copied = true
+			}
+
+			rawCells[cellPos {r, c}] = value
+			escaped[r][c] = escapeValue(value)
+		}
+	}
+
+	return escaped
+}
+
+func columnName(column int) string {
+	if column < len(rawColumns) {
+		return rawColumns[column]
+	}
+
+	return results.Columns[column].Name
+}
+
+func cellValue(row, column int) string {
+	if raw, ok := rawCells[cellPos {row, column}]; ok {
+		return raw
+	}
+
+	return results.Rows[row][column]
+}
+
+// The results as they came from the server, undoing escapeRows.
+func rawResultRows() [][]string {
+	if len(rawCells) == 0 {
+		return results.Rows
+	}
+
+	rows := make([][]string, len(results.Rows))
+	for r, row := range results.Rows {
+		rows[r] = make([]string, len(row))
+		for c := range row {
+			rows[r][c] = cellValue(r, c)
+		}
+	}
+
+	return rows
+}
+
+// Text with line breaks and tabs is shown as it is, anything else that
+// can't be drawn as a hex dump.
+func cellViewerText(value string) string {
+	text := strings.Replace(value, "\r\n", "\n", -1)
+	unprintable := func(r rune) bool {
+		return unicode.IsControl(r) && r != '\n' && r != '\t'
+	}
+
+	if utf8.ValidString(text) && strings.IndexFunc(text, unprintable) < 0 {
+		return text
+	}
+
+	return hex.Dump([]byte(value))
+}
+
+func handleCellViewerEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey || ev.Ch != 'v' {
+		return false
+	}
+
+	row, column := results.SelectedRow, results.SelectedColumn
+	if row >= len(results.Rows) || column >= len(results.Rows[row]) {
+		return false
+	}
+
+	title := fmt.Sprintf("%s, row %d", columnName(column), row + 1)
+	value := cellValue(row, column)
+
+	protocol := imageProtocol()
+	if canPreview(value, protocol) {
+		hex, err := previewImage(title, []byte(value), protocol)
+		if err != nil {
+			showToast(trf("Can't preview the image: %s", err), true)
+		} else if !hex {
+			return true
+		}
+	}
+
+	text := cellViewerText(value)
+	if raw, ok := geometryCells[cellPos {row, column}]; ok {
+		text += "\n\n" + hex.Dump(raw)
+	}
+
+	showPopup(title, text, false)
+	return true
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"errors"
+	"strings"
+)
+
+type modelColumn struct {
+	name     string
+	dataType string
+	unsigned bool
+	nullable bool
+	boolean  bool
+}
+
+type modelGenerator func(table string, columns []modelColumn) string
+
+var modelGenerators = map[string]modelGenerator {
+	"go":         goModel,
+	"typescript": typeScriptModel,
+	"ts":         typeScriptModel,
+	"python":     pythonModel,
+	"py":         pythonModel,
+}
+
+var initialisms = newWordSet("API ID IP JSON SQL URL UUID HTTP")
+
+func init() {
+	registerCommand(command {
+		name:  "model",
+		usage: "<table> [go|ts|py]",
+		help:  "Generate a struct or class for a table's columns",
+		run:   modelCommand,
+	})
+}
+
+func loadModelColumns(database, table string) ([]modelColumn, error) {
+	rows, err := queryStrings("SELECT column_name, data_type, " +
+				  "column_type, is_nullable " +
+				  "FROM information_schema.columns " +
+				  "WHERE table_schema = ? AND table_name = ? " +
+				  "ORDER BY ordinal_position", database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New(trf("No table named %s", table))
+	}
+
+	columns := []modelColumn {}
+
+	for _, row := range rows {
+		columns = append(columns, modelColumn {
+			name:     row[0],
+			dataType: strings.ToLower(row[1]),
+			unsigned: strings.Contains(row[2], "unsigned"),
+			nullable: row[3] == "YES",
+			boolean:  strings.HasPrefix(row[2], "tinyint(1)"),
+		})
+	}
+
+	return columns, nil
+}
+
+// order_id -> OrderID
+func camelCase(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !isWordChar(r) || r == '_'
+	})
+
+	for i, part := range parts {
+		if initialisms.contains(part) {
+			parts[i] = strings.ToUpper(part)
+		} else {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+
+	return strings.Join(parts, "")
+}
+
+func goType(c modelColumn) string {
+	var base, null string
+
+	switch c.dataType {
+	case "tinyint":
+		base, null = "int8", "sql.NullInt16"
+		if c.boolean {
+			base, null = "bool", "sql.NullBool"
+		}
+	case "smallint":
+		base, null = "int16", "sql.NullInt16"
+	case "mediumint", "int", "integer":
+		base, null = "int32", "sql.NullInt32"
+	case "bigint":
+		base, null = "int64", "sql.NullInt64"
+	case "float", "double", "real":
+		base, null = "float64", "sql.NullFloat64"
+	case "date", "datetime", "timestamp":
+		base, null = "time.Time", "sql.NullTime"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob",
+	     "longblob", "bit":
+		base, null = "[]byte", "[]byte"
+	case "json":
+		base, null = "json.RawMessage", "json.RawMessage"
+	default:
+		base, null = "string", "sql.NullString"
+	}
+
+	if c.unsigned && strings.HasPrefix(base, "int") {
+		base = "u" + base
+	}
+
+	if c.nullable {
+		return null
+	}
+
+	return base
+}
+
+func goModel(table string, columns []modelColumn) string {
+	nameWidth, typeWidth := 0, 0
+
+	for _, c := range columns {
+		if len(camelCase(c.name)) > nameWidth {
+			nameWidth = len(camelCase(c.name))
+		}
+
+		if len(goType(c)) > typeWidth {
+			typeWidth = len(goType(c))
+		}
+	}
+
+	code := fmt.Sprintf("type %s struct {\n", camelCase(table))
+
+	for _, c := range columns {
+		code += fmt.Sprintf("\t%-*s %-*s `db:\"%s\" json:\"%s\"`\n",
+				    nameWidth, camelCase(c.name),
+				    typeWidth, goType(c), c.name, c.name)
+	}
+
+	return code + "}\n"
+}
+
+func scriptType(c modelColumn, numberType, stringType, boolType,
+		dateType, bytesType string) string {
+	switch c.dataType {
+	case "tinyint":
+		if c.boolean {
+			return boolType
+		}
+		return numberType
+	case "smallint", "mediumint", "int", "integer", "bigint", "float",
+	     "double", "real":
+		return numberType
+	case "date", "datetime", "timestamp":
+		return dateType
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob",
+	     "longblob", "bit":
+		return bytesType
+	}
+
+	return stringType
+}
+
+func typeScriptModel(table string, columns []modelColumn) string {
+	code := fmt.Sprintf("export interface %s {\n", camelCase(table))
+
+	for _, c := range columns {
+		t := scriptType(c, "number", "string", "boolean", "Date",
+				"Uint8Array")
+		if c.nullable {
+			t += " | null"
+		}
+
+		code += fmt.Sprintf("  %s: %s;\n", c.name, t)
+	}
+
+	return code + "}\n"
+}
+
+func pythonModel(table string, columns []modelColumn) string {
+	code := fmt.Sprintf("@dataclass\nclass %s:\n", camelCase(table))
+
+	for _, c := range columns {
+		t := scriptType(c, "int", "str", "bool", "datetime", "bytes")
+
+		switch c.dataType {
+		case "float", "double", "real":
+			t = "float"
+		case "decimal":
+			t = "Decimal"
+		}
+
+		if c.nullable {
+			t = "Optional[" + t + "]"
+		}
+
+		code += fmt.Sprintf("    %s: %s\n", c.name, t)
+	}
+
+	return code
+}
+
+func modelCommand(args []string) error {
+	language := "go"
+
+	switch len(args) {
+	case 1:
+	case 2:
+		language = args[1]
+	default:
+		return usageError("model")
+	}
+
+	generate, ok := modelGenerators[language]
+	if !ok {
+		return errors.New(trf("Unknown language '%s'", language))
+	}
+
+	database, table := splitTableName(args[0])
+
+	columns, err := loadModelColumns(database, table)
+	if err != nil {
+		return err
+	}
+
+	showPopup(trf("Model for %s", table), generate(table, columns), false)
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"errors"
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(args []string) error
+}
+
+// A single line of input read in the status bar, used by the command
+// palette and anything else that needs to ask the user for text.
+type prompt struct {
+	active   bool
+	label    string
+	input    []rune
+	secret   bool
+	onSubmit func(input string)
+}
+
+var commands = map[string]command {}
+var input prompt
+
+// The names of the macros and tools in commands, which are replaced when
+// config.json is reloaded. Everything else there is built in.
+var userCommands = map[string]bool {}
+
+func registerCommand(c command) {
+	commands[c.name] = c
+}
+
+func registerUserCommand(c command) {
+	userCommands[c.name] = true
+	registerCommand(c)
+}
+
+func unregisterUserCommands() {
+	for name := range userCommands {
+		delete(commands, name)
+	}
+
+	userCommands = map[string]bool {}
+}
+
+// Macros and tools can't take the name of a built-in command, or of each
+// other, since one would hide the other.
+func checkCommandNames(macros map[string]Macro, tools map[string]Tool) error {
+	names := []string {}
+	for name := range macros {
+		names = append(names, name)
+	}
+	for name := range tools {
+		if _, ok := macros[name]; ok {
+			return errors.New(trf("%s is both a macro and a tool",
+					      name))
+		}
+
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		if _, ok := commands[name]; ok && !userCommands[name] {
+			return errors.New(trf("%s is a built-in command", name))
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	registerCommand(command {
+		name: "help",
+		help: "List the available commands",
+		run:  helpCommand,
+	})
+}
+
+func helpCommand(args []string) error {
+	names := []string {}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	text := ""
+	for _, name := range names {
+		c := commands[name]
+		text += fmt.Sprintf("%-30s %s\n", c.name + " " + c.usage,
+				    tr(c.help))
+	}
+
+	showPopup(tr("Commands"), text, false)
+	return nil
+}
+
+func askFor(label string, onSubmit func(string)) {
+	input = prompt {
+		active:   true,
+		label:    label,
+		onSubmit: onSubmit,
+	}
+
+	status.Text = label
+}
+
+// Like askFor, but shows what is typed as asterisks.
+func askForSecret(label string, onSubmit func(string)) {
+	askFor(label, onSubmit)
+	input.secret = true
+}
+
+func (p prompt) String() string {
+	if p.secret {
+		return p.label + strings.Repeat("*", len(p.input))
+	}
+
+	return p.label + string(p.input)
+}
+
+func confirm(question string, onYes func()) {
+	askFor(question + tr(" (y/n) "), func(answer string) {
+		if answer == "y" || answer == "yes" {
+			onYes()
+		}
+	})
+}
+
+func openCommandPalette() {
+	askFor(":", runCommand)
+}
+
+func runCommand(line string) {
+	if err := execCommand(line); err != nil {
+		showError(err.Error())
+	}
+}
+
+func execCommand(line string) error {
+	// The rest of the line goes to the shell as typed, quotes and all.
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string {"\\!", "!"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return shellEscape(strings.TrimSpace(trimmed[len(prefix):]))
+		}
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	c, ok := commands[fields[0]]
+	if !ok {
+		return runSavedQuery(fields[0], fields[1:])
+	}
+
+	return c.run(fields[1:])
+}
+
+func usageError(name string) error {
+	return errors.New(trf("Usage: %s %s", name, commands[name].usage))
+}
+
+func handlePromptEvent(ev escapebox.Event) bool {
+	if !input.active || ev.Type != termbox.EventKey {
+		return false
+	}
+
+	switch ev.Key {
+	case termbox.KeyEnter:
+		input.active = false
+		status.Text = ""
+		input.onSubmit(string(input.input))
+		return true
+
+	case termbox.KeyEsc, termbox.KeyCtrlC:
+		input.active = false
+		status.Text = ""
+		return true
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(input.input) > 0 {
+			input.input = input.input[:len(input.input) - 1]
+		}
+
+	case termbox.KeySpace:
+		input.input = append(input.input, ' ')
+
+	default:
+		if ev.Ch != 0 {
+			input.input = append(input.input, ev.Ch)
+		}
+	}
+
+	status.Text = input.String()
+	return true
+}
+
+// Splits an optionally database-qualified table name like shop.orders,
+// defaulting to the connection's database.
+func splitTableName(name string) (string, string) {
+	name = strings.Replace(name, "`", "", -1)
+
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i + 1:]
+	}
+
+	return config.Database, name
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"errors"
+	"database/sql"
+)
+
+// Statements that only read, which are safe to run on two servers to
+// compare what they return, unless they contain one of writeWords.
+var comparableStatements = map[string]bool {
+	"SELECT":   true,
+	"WITH":     true,
+	"TABLE":    true,
+	"VALUES":   true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"EXPLAIN":  true,
+	"CHECKSUM": true,
+}
+
+// Words that make an otherwise reading statement write: EXPLAIN ANALYZE
+// runs the statement it explains, a WITH can end in (or, in PostgreSQL,
+// contain) an UPDATE or DELETE, and SELECT ... INTO writes a file or
+// variables.
+var writeWords = newWordSet(`ANALYZE INSERT UPDATE DELETE REPLACE MERGE
+	INTO CALL`)
+
+func init() {
+	registerCommand(command {
+		name:  "compare",
+		usage: "<profile> <profile> [key column]",
+		help:  "Run the statement on two connections and compare them",
+		run:   compareCommand,
+	})
+}
+
+func onlyReads(query string) bool {
+	if !comparableStatements[leadingWords(query)[0]] {
+		return false
+	}
+
+	text := []rune(query)
+	for _, t := range lex(text, sqlDialect) {
+		if t.kind == tokenWord &&
+		   writeWords.contains(string(text[t.start:t.end])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Runs a query on a connection of its own, closed again afterwards, inside
+// a read-only transaction as a second line of defence against writes.
+func fetchFromProfile(name, query string) (resultSet, error) {
+	conn := profileConnection(name)
+	if conn.Driver == "" {
+		return resultSet {}, errors.New(trf("No profile named %s",
+						    name))
+	}
+
+	handle, err := openConnection(conn)
+	if err != nil {
+		return resultSet {}, fmt.Errorf("%s: %s", name, err)
+	}
+	defer handle.Close()
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	tx, err := handle.BeginTx(ctx, &sql.TxOptions { ReadOnly: true })
+	if err != nil {
+		return resultSet {}, fmt.Errorf("%s: %s", name, err)
+	}
+	defer tx.Rollback()
+
+	res, err := queryStatement(ctx, tx, query)
+	if err != nil {
+		return resultSet {}, fmt.Errorf("%s: %s", name, err)
+	}
+	defer res.Close()
+
+	columns, rows, err := scanStrings(res)
+	if err != nil {
+		return resultSet {}, fmt.Errorf("%s: %s", name, err)
+	}
+
+	return resultSet { columns, rows }, nil
+}
+
+func compareCommand(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return usageError("compare")
+	}
+
+	query := currentQuery()
+	if query == "" {
+		return errors.New(tr("There is no statement under the cursor"))
+	}
+
+	if !onlyReads(query) {
+		return errors.New(tr("Only statements that read can " +
+				     "be compared"))
+	}
+
+	leftName, rightName := args[0], args[1]
+	key := ""
+	if len(args) == 3 {
+		key = args[2]
+	}
+
+	status.Text = trf("Running on %s and %s...", leftName, rightName)
+
+	go func() {
+		defer recoverCrash()
+
+		var left, right resultSet
+		var leftErr, rightErr error
+		done := make(chan bool)
+
+		go func() {
+			defer recoverCrash()
+			right, rightErr = fetchFromProfile(rightName, query)
+			done <- true
+		}()
+
+		left, leftErr = fetchFromProfile(leftName, query)
+		<-done
+
+		post(func() {
+			status.Text = ""
+
+			err := leftErr
+			if err == nil {
+				err = rightErr
+			}
+
+			if err != nil {
+				showError(err.Error())
+				return
+			}
+
+			labels := diffLabels {
+				leftOnly:  trf("only in %s", leftName),
+				rightOnly: trf("only in %s", rightName),
+			}
+
+			compared, summary, err := diffResultSets(left, right,
+								 key, labels)
+			if err != nil {
+				showError(err.Error())
+				return
+			}
+
+			showResults(compared.columns, compared.rows)
+			showMessage(summary)
+		})
+	}()
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"flag"
+	"errors"
+	"strings"
+	"path/filepath"
+	"encoding/json"
+)
+
+const defaultTabWidth int = 4
+
+const configPath string = "config.json"
+
+type Config struct {
+	Connection
+
+	TabWidth     int               `json:"tab_width"`
+	InsertSpaces bool              `json:"insert_spaces"`
+	Theme        string            `json:"theme"`
+	Colors       map[string]string `json:"colors"`
+	Overview     bool              `json:"overview"`
+	Locale       string            `json:"locale"`
+	ReducedColor bool              `json:"reduced_color"`
+
+	// "off" (the default), "error", "info" or "debug", and where to write
+	// the log (prequel.log in the config directory by default).
+	LogLevel string `json:"log_level"`
+	LogFile  string `json:"log_file"`
+
+	// Seconds a statement may run before it's cancelled; 0 for no limit.
+	QueryTimeout int `json:"query_timeout"`
+
+	// Megabytes of results to fetch into the results view; 0 for no limit.
+	MaxMemory int `json:"max_memory"`
+
+	// Estimated rows a full scan may read before running a statement from
+	// the editor asks first; 0 to never ask.
+	ScanWarning int `json:"scan_warning"`
+
+	// Connection pool limits (0 for database/sql's defaults), for running
+	// several live views at once. The lifetime is in seconds.
+	PoolSize     int `json:"pool_size"`
+	PoolIdle     int `json:"pool_idle"`
+	PoolLifetime int `json:"pool_lifetime"`
+
+	// Unset means detect it from the terminal.
+	Truecolor *bool `json:"truecolor"`
+
+	// Number formatting in the results view: commas between thousands,
+	// and how many decimal places to round fractions to (unset to show
+	// them as they come).
+	Thousands bool `json:"thousands"`
+	Decimals  *int `json:"decimals"`
+
+	// A strftime format, like "%d.%m.%Y %H:%M", for dates and times in the
+	// results view.
+	DateFormat string `json:"date_format"`
+
+	// The time zone to show DATETIME and TIMESTAMP values in, and the one
+	// the server sends them in (UTC unless set).
+	Timezone   string `json:"timezone"`
+	DBTimezone string `json:"db_timezone"`
+
+	// How to preview images in the cell viewer: "auto" (the default),
+	// "sixel", "iterm" or "off".
+	Images string `json:"images"`
+
+	// How to tell the user a long query has finished: "bell", "osc" or
+	// "off", and after how many seconds.
+	Notify      string `json:"notify"`
+	NotifyAfter int    `json:"notify_after"`
+
+	// The query library file, if not library.json in the config directory.
+	Library string `json:"library"`
+
+	// Named connections to use instead of the top-level one.
+	Profiles map[string]Connection `json:"profiles"`
+
+	// Palette commands to run on F1, F10, F11 or F12.
+	Keys map[string]string `json:"keys"`
+
+	// Lint rules to turn off (or back on), all on by default.
+	Lint map[string]bool `json:"lint"`
+
+	Macros map[string]Macro `json:"macros"`
+	Tools  map[string]Tool  `json:"tools"`
+	Hooks  Hooks            `json:"hooks"`
+}
+
+// A named sequence of SQL statements and commands (starting with !) run
+// from the command palette. :name placeholders are filled in from the
+// command's arguments.
+type Macro struct {
+	Help  string   `json:"help"`
+	Steps []string `json:"steps"`
+}
+
+var profileName string
+
+func init() {
+	flag.StringVar(&profileName, "profile", "",
+		       "Connect using this profile from config.json")
+}
+
+func parseConfig(configBytes []byte) (Config, error) {
+	config := Config {
+		TabWidth:    defaultTabWidth,
+		Notify:      "bell",
+		NotifyAfter: defaultNotifyAfter,
+		MaxMemory:   defaultMaxMemory,
+	}
+
+	err := json.Unmarshal(configBytes, &config)
+	if err != nil {
+		return config, err
+	}
+
+	if config.QueryTimeout < 0 {
+		return config, errors.New(tr("The query_timeout setting " +
+					     "can't be negative"))
+	}
+
+	if config.ScanWarning < 0 {
+		return config, errors.New(tr("The scan_warning setting can't " +
+					     "be negative"))
+	}
+
+	if config.PoolSize < 0 || config.PoolIdle < 0 || config.PoolLifetime < 0 {
+		return config, errors.New(tr("The pool settings can't be " +
+					     "negative"))
+	}
+
+	if config.TabWidth < 1 {
+		config.TabWidth = defaultTabWidth
+	}
+
+	if err := checkKeys(config.Keys); err != nil {
+		return config, err
+	}
+
+	if err := checkLint(config.Lint); err != nil {
+		return config, err
+	}
+
+	if err := checkImages(config.Images); err != nil {
+		return config, err
+	}
+
+	if err := checkTimezones(config); err != nil {
+		return config, err
+	}
+
+	if err := checkDecimals(config.Decimals); err != nil {
+		return config, err
+	}
+
+	if err := checkDateFormat(config.DateFormat); err != nil {
+		return config, err
+	}
+
+	if err := checkCommandNames(config.Macros, config.Tools); err != nil {
+		return config, err
+	}
+
+	switch config.Notify {
+	case "bell", "osc", "off":
+	default:
+		return config, errors.New(trf("Invalid notify setting '%s'",
+					      config.Notify))
+	}
+
+	return config, nil
+}
+
+// Returns the named profile's connection, or the top-level connection if
+// no name is given.
+func (c Config) profile(name string) (Connection, error) {
+	if name == "" {
+		return c.Connection, nil
+	}
+
+	conn, ok := c.Profiles[name]
+	if !ok {
+		return conn, errors.New(trf("No profile named '%s'", name))
+	}
+
+	return conn, nil
+}
+
+// An external program run from the command palette. It is given the
+// current statement (input "statement") or the results as JSON (input
+// "results") on stdin, and its output is shown in a popup, inserted into
+// the editor ("editor") or replaces the statement ("replace").
+type Tool struct {
+	Command []string `json:"command"`
+	Input   string   `json:"input"`
+	Output  string   `json:"output"`
+	Help    string   `json:"help"`
+}
+
+// Run around every statement sent to the server. Statements matching a
+// Deny pattern are refused, as are those the Before command exits non-zero
+// for. After commands and the webhook are told how each statement went.
+type Hooks struct {
+	Deny    []string `json:"deny"`
+	Before  []string `json:"before"`
+	After   []string `json:"after"`
+	Webhook string   `json:"webhook"`
+}
+
+// Per-user files (autosaves and the like) live under $XDG_CONFIG_HOME/prequel,
+// falling back to ~/.config/prequel.
+func configDir() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+
+		base = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(base, "prequel")
+}
+
+// A readable name for a connection, e.g. root@localhost:3306/shop.
+func (c Connection) String() string {
+	if dialectForDriver(c.Driver) == dialectSQLite {
+		name := strings.TrimPrefix(c.Database, "file:")
+		return strings.SplitN(name, "?", 2)[0]
+	}
+
+	return fmt.Sprintf("%s@%s:%d/%s", c.User, c.Host, c.Port, c.Database)
+}
+
+// A filesystem-safe name for a connection, e.g. root@localhost_3306_shop.
+func (c Connection) slug() string {
+	name := fmt.Sprintf("%s@%s_%d_%s", c.User, c.Host, c.Port, c.Database)
+
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
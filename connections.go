@@ -0,0 +1,442 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"time"
+	"errors"
+	"context"
+	"strconv"
+	"strings"
+	"io/ioutil"
+	"database/sql"
+	"encoding/json"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+// Names the top-level connection in config.json in the connection manager.
+const defaultProfile string = "(default)"
+
+const connectionTestTimeout = 5 * time.Second
+
+// The top-level connection from config.json, which config.Connection stops
+// being once a profile is picked.
+var baseConnection Connection
+
+func init() {
+	registerCommand(command {
+		name: "connections",
+		help: "Add, edit, test and switch between connection profiles",
+		run:  connectionsCommand,
+	})
+}
+
+func profileNames() []string {
+	names := []string {}
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return append([]string {defaultProfile}, names...)
+}
+
+func profileConnection(name string) Connection {
+	if name == defaultProfile {
+		return baseConnection
+	}
+
+	return config.Profiles[name]
+}
+
+func showConnections() {
+	rows := [][]string {}
+
+	for _, name := range profileNames() {
+		conn := profileConnection(name)
+
+		active := ""
+		if name == profileName || name == defaultProfile &&
+		   profileName == "" {
+			active = "*"
+		}
+
+		rows = append(rows, []string {active, name, conn.Driver,
+			conn.Host, strconv.Itoa(conn.Port), conn.User,
+			conn.Database})
+	}
+
+	showResults([]string {"", "profile", "driver", "host", "port", "user",
+			      "database"}, rows)
+	resultsView = "connections"
+
+	status.Text = tr("Enter: connect, a: add, e: edit, d: delete, t: test")
+}
+
+func connectionsCommand(args []string) error {
+	showConnections()
+	return nil
+}
+
+// Rewrites only the connection settings in config.json, leaving everything
+// else as it was apart from the formatting.
+func saveConnections() error {
+	data, err := ioutil.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		data, err = []byte("{}"), nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	settings := map[string]json.RawMessage {}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+
+	fields, err := json.Marshal(baseConnection)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(fields, &settings); err != nil {
+		return err
+	}
+
+	settings["profiles"], err = json.Marshal(config.Profiles)
+	if err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(settings, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	// The password is in there, so it isn't left readable to others.
+	if err := ioutil.WriteFile(configPath + ".tmp", append(data, '\n'),
+				   0600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(configPath + ".tmp", configPath); err != nil {
+		return err
+	}
+
+	noteConfigWritten()
+	return nil
+}
+
+// Blank answers keep the value in brackets.
+func askForProfileFields(conn Connection, fields []string,
+			 done func(Connection)) {
+	if len(fields) == 0 {
+		done(conn)
+		return
+	}
+
+	field, rest := fields[0], fields[1:]
+	next := func(conn Connection) {
+		askForProfileFields(conn, rest, done)
+	}
+
+	switch field {
+	case "driver":
+		if conn.Driver == "" {
+			conn.Driver = "mysql"
+		}
+
+		askFor(trf("Driver [%s]: ", conn.Driver), func(value string) {
+			if value != "" {
+				conn.Driver = value
+			}
+			next(conn)
+		})
+
+	case "host":
+		askFor(trf("Host [%s]: ", conn.Host), func(value string) {
+			if value != "" {
+				conn.Host = value
+			}
+			next(conn)
+		})
+
+	case "port":
+		if conn.Port == 0 {
+			conn.Port = 3306
+		}
+
+		askFor(trf("Port [%d]: ", conn.Port), func(value string) {
+			if value != "" {
+				port, err := strconv.Atoi(value)
+				if err != nil || port <= 0 || port > 65535 {
+					showError(trf("Invalid port '%s'", value))
+					return
+				}
+				conn.Port = port
+			}
+			next(conn)
+		})
+
+	case "user":
+		askFor(trf("User [%s]: ", conn.User), func(value string) {
+			if value != "" {
+				conn.User = value
+			}
+			next(conn)
+		})
+
+	case "password":
+		label := tr("Password (blank to keep, - for none): ")
+		askForSecret(label, func(value string) {
+			switch value {
+			case "":
+			case "-":
+				conn.Password = ""
+			default:
+				conn.Password = value
+			}
+			next(conn)
+		})
+
+	case "database":
+		askFor(trf("Database [%s]: ", conn.Database),
+		       func(value string) {
+			if value != "" {
+				conn.Database = value
+			}
+			next(conn)
+		})
+	}
+}
+
+var profileFields = []string {"driver", "host", "port", "user", "password",
+			      "database"}
+
+func storeProfile(name string, conn Connection) {
+	if name == defaultProfile {
+		baseConnection = conn
+	} else {
+		if config.Profiles == nil {
+			config.Profiles = map[string]Connection {}
+		}
+		config.Profiles[name] = conn
+	}
+
+	if err := saveConnections(); err != nil {
+		showError(trf("Saving %s failed: %s", configPath, err))
+		return
+	}
+
+	showConnections()
+	showMessage(trf("Saved profile %s", name))
+}
+
+func addProfile() {
+	askFor(tr("New profile name: "), func(name string) {
+		if name == "" || name == defaultProfile {
+			showError(tr("A profile needs a name"))
+			return
+		}
+
+		if _, ok := config.Profiles[name]; ok {
+			showError(trf("There is already a profile named %s",
+				      name))
+			return
+		}
+
+		askForProfileFields(Connection {}, profileFields,
+				    func(conn Connection) {
+			storeProfile(name, conn)
+		})
+	})
+}
+
+func deleteProfile(name string) {
+	if name == defaultProfile {
+		showError(tr("The default connection can't be deleted"))
+		return
+	}
+
+	confirm(trf("Delete profile %s?", name), func() {
+		delete(config.Profiles, name)
+
+		if err := saveConnections(); err != nil {
+			showError(trf("Saving %s failed: %s", configPath, err))
+			return
+		}
+
+		showConnections()
+		showMessage(trf("Deleted profile %s", name))
+	})
+}
+
+func openConnection(conn Connection) (*sql.DB, error) {
+	handle, err := connect(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(appContext,
+					   connectionTestTimeout)
+	defer cancel()
+
+	if err := handle.PingContext(ctx); err != nil {
+		handle.Close()
+		return nil, err
+	}
+
+	return handle, nil
+}
+
+func testProfile(name string) {
+	conn := profileConnection(name)
+	status.Text = trf("Connecting to %s...", conn)
+
+	go func() {
+		defer recoverCrash()
+		handle, err := openConnection(conn)
+		if err == nil {
+			handle.Close()
+		}
+
+		post(func() {
+			if err != nil {
+				showToast(trf("%s: %s", name, err), true)
+				return
+			}
+
+			showToast(trf("Connected to %s", conn), false)
+		})
+	}()
+}
+
+// Switches the whole UI over to another connection. The editor keeps its
+// text, and the old connection is closed once the new one works. An open
+// transaction is committed or rolled back first, as on quitting.
+func useProfile(name string) error {
+	if sourceRunning {
+		return errors.New(tr("Wait for the running script to finish"))
+	}
+
+	// Dumps, exports and imports read db from their own goroutines.
+	if jobRunning() {
+		return errors.New(tr("Wait for the background job to finish"))
+	}
+
+	if !inTransaction {
+		return switchProfile(name)
+	}
+
+	question := trf("Switch connections? a transaction is open " +
+			"(%s, %s, %s) ", tr("c: commit"), tr("r: roll back"),
+			tr("Enter: stay"))
+
+	askFor(question, func(answer string) {
+		statement := map[string]string {
+			"c": "COMMIT",
+			"r": "ROLLBACK",
+		}[strings.TrimSpace(answer)]
+
+		if statement == "" {
+			return
+		}
+
+		if err := endTransaction(statement); err != nil {
+			showError(trf("%s failed: %s", statement, err))
+			return
+		}
+
+		if err := useProfile(name); err != nil {
+			showError(err.Error())
+		}
+	})
+
+	return nil
+}
+
+func switchProfile(name string) error {
+	conn := profileConnection(name)
+	if conn.Driver == "" || conn.Database == "" {
+		return errors.New(tr("The profile needs a driver and a database"))
+	}
+
+	handle, err := openConnection(conn)
+	if err != nil {
+		return err
+	}
+
+	stopLiveView()
+	resetEditorConnection()
+	db.Close()
+	db = handle
+
+	profileName = name
+	if name == defaultProfile {
+		profileName = ""
+	}
+
+	config.Connection = conn
+	sqlDialect = dialectForDriver(conn.Driver)
+	registerMacros(config.Macros)
+	logEvent(levelInfo, "connected", "to", conn, "profile", name)
+	showIdentity(conn)
+	resizeHandler()
+
+	showConnections()
+	showMessage(trf("Connected to %s", conn))
+
+	// The schema browser is loaded again the next time it's opened.
+	browser.roots = nil
+	if sidebarVisible {
+		if err := browser.load(); err != nil {
+			showError(err.Error())
+		}
+		browser.refresh()
+	}
+
+	return nil
+}
+
+func handleConnectionsEvent(ev escapebox.Event) bool {
+	if resultsView != "connections" || ev.Type != termbox.EventKey {
+		return false
+	}
+
+	name := ""
+	if results.SelectedRow < len(results.Rows) {
+		name = cellValue(results.SelectedRow, 1)
+	}
+
+	switch {
+	case ev.Ch == 'a':
+		addProfile()
+
+	case name == "":
+		return false
+
+	case ev.Key == termbox.KeyEnter:
+		if err := useProfile(name); err != nil {
+			showError(err.Error())
+		}
+
+	case ev.Ch == 'e':
+		askForProfileFields(profileConnection(name), profileFields,
+				    func(conn Connection) {
+			storeProfile(name, conn)
+		})
+
+	case ev.Ch == 'd':
+		deleteProfile(name)
+
+	case ev.Ch == 't':
+		testProfile(name)
+
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"time"
+	"context"
+)
+
+// Everything sent to the server runs under this, so cancelling it on exit
+// stops whatever is still running in the background.
+var appContext, cancelAll = context.WithCancel(context.Background())
+
+// Returns the context for a single statement, which ends when the statement
+// has run for query_timeout seconds (if set) or prequel exits. The cancel
+// func must be called once the statement's results have been read.
+func queryContext() (context.Context, context.CancelFunc) {
+	if config.QueryTimeout > 0 {
+		timeout := time.Duration(config.QueryTimeout) * time.Second
+		return context.WithTimeout(appContext, timeout)
+	}
+
+	return context.WithCancel(appContext)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"github.com/nsf/termbox-go"
+)
+
+// The least contrast allowed in reduced-color mode: for text against its
+// background, and between the background and the shades that mark the
+// statement, the selection and errors.
+const minTextContrast float64 = 4.5
+const minShadeContrast float64 = 1.6
+
+func attributeRGB(a termbox.Attribute) (uint8, uint8, uint8) {
+	if isRGB(a) {
+		return termbox.AttributeToRGB(a)
+	}
+
+	return paletteRGB(int(a) - 1)
+}
+
+// Relative luminance as defined by WCAG, from 0 (black) to 1 (white).
+func luminance(a termbox.Attribute) float64 {
+	r, g, b := attributeRGB(a)
+
+	linear := func(c uint8) float64 {
+		v := float64(c) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v + 0.055) / 1.055, 2.4)
+	}
+
+	return 0.2126 * linear(r) + 0.7152 * linear(g) + 0.0722 * linear(b)
+}
+
+func contrast(a, b termbox.Attribute) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// Returns color if it stands out enough from background, otherwise blends
+// it toward white (on dark backgrounds) or black until it does, so colors
+// keep roughly their hue.
+func ensureContrast(color, background termbox.Attribute,
+		    minimum float64) termbox.Attribute {
+	if color == termbox.ColorDefault ||
+	   contrast(color, background) >= minimum {
+		return color
+	}
+
+	target := 0.0
+	if luminance(background) < 0.5 {
+		target = 255
+	}
+
+	r, g, b := attributeRGB(color)
+	blend := func(c uint8, amount float64) uint8 {
+		return uint8(float64(c) + (target - float64(c)) * amount + 0.5)
+	}
+
+	for step := 1; step < 10; step++ {
+		amount := float64(step) / 10
+		blended := termbox.RGBToAttribute(blend(r, amount),
+						  blend(g, amount),
+						  blend(b, amount))
+
+		if contrast(blended, background) >= minimum {
+			return blended
+		}
+	}
+
+	return termbox.RGBToAttribute(uint8(target), uint8(target),
+				      uint8(target))
+}
+
+// Drops the alternating row shade and strengthens colors that are too
+// close to the background to tell apart on some terminals or for some
+// eyes. Themes using the terminal's default background are left as they
+// are, since there's no telling what it is.
+func (t Theme) reduced() Theme {
+	t.RowBgAlt = t.RowBg
+
+	bg := t.Background
+	if bg == termbox.ColorDefault {
+		return t
+	}
+
+	for _, fg := range []*termbox.Attribute {&t.Text, &t.Keyword, &t.Type,
+		&t.Function, &t.String, &t.Number, &t.Identifier, &t.Comment} {
+		*fg = ensureContrast(*fg, bg, minTextContrast)
+	}
+
+	for _, shade := range []*termbox.Attribute {&t.Statement,
+		&t.SelectedBg, &t.Error, &t.Warning} {
+		*shade = ensureContrast(*shade, bg, minShadeContrast)
+	}
+
+	return t
+}
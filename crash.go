@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"io/ioutil"
+	"path/filepath"
+	"runtime/debug"
+	"github.com/briansteffens/tui"
+)
+
+// Set once the terminal is in raw mode, so a crash knows to restore it.
+var uiStarted bool
+
+// Writes what went wrong and where to a file in the config directory,
+// returning its path (or why it couldn't be written).
+func writeCrashLog(value interface{}, stack []byte) string {
+	dir := filepath.Join(configDir(), "crashes")
+	path := filepath.Join(dir, time.Now().Format("20060102-150405") +
+			      ".log")
+
+	text := fmt.Sprintf("prequel crashed at %s: %v\n\n%s",
+			    time.Now().Format(time.RFC3339), value, stack)
+
+	err := os.MkdirAll(dir, 0700)
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(text), 0600)
+	}
+
+	if err != nil {
+		path = fmt.Sprintf("(not written: %s)", err)
+	}
+
+	logEvent(levelError, "crash", "value", value, "details", path)
+	return path
+}
+
+// Puts the terminal back the way it was, saves the editor and explains the
+// crash on stderr instead of leaving a raw-mode screen full of stack trace.
+func crash(value interface{}) {
+	path := writeCrashLog(value, debug.Stack())
+
+	if uiStarted {
+		disableFocusReporting()
+		disableBracketedPaste()
+		tui.Close()
+		restoreTerminalTitle()
+	}
+
+	// The journal is kept, to be offered back on the next launch.
+	autosave.flush()
+	journal.flush()
+
+	fmt.Fprintf(os.Stderr, "prequel crashed: %v\nDetails: %s\n", value,
+		    path)
+	os.Exit(2)
+}
+
+// Deferred at the top of main and of every goroutine, since a panic in any
+// of them ends the program.
+func recoverCrash() {
+	value := recover()
+
+	switch value.(type) {
+	case nil, quitRequest:
+		return
+	}
+
+	crash(value)
+}
+
+// A panic while handling one event is logged and shown in the status bar,
+// and the UI carries on.
+func recoverEvent() {
+	value := recover()
+	if value == nil {
+		return
+	}
+
+	// Not a crash: passed on for main to exit.
+	if _, ok := value.(quitRequest); ok {
+		panic(value)
+	}
+
+	path := writeCrashLog(value, debug.Stack())
+	showError(trf("Internal error: %v (details in %s)", value, path))
+}
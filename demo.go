@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"database/sql"
+)
+
+// Shared so the UI and background jobs, which use connections of their own,
+// all see the same in-memory database.
+const demoDSN string = "file:prequel-demo?mode=memory&cache=shared"
+
+var demoMode bool
+
+// SQLite throws an in-memory database away when its last connection closes,
+// so one is held open for as long as prequel runs.
+var demoConn *sql.Conn
+
+var demoConnection = Connection {
+	Driver:   "sqlite3",
+	Database: demoDSN,
+}
+
+var demoSchema = []string {
+	`CREATE TABLE customers (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		email TEXT NOT NULL UNIQUE,
+		country TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	)`,
+
+	`CREATE TABLE products (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		category TEXT NOT NULL,
+		price REAL NOT NULL
+	)`,
+
+	`CREATE TABLE orders (
+		id INTEGER PRIMARY KEY,
+		customer_id INTEGER NOT NULL REFERENCES customers(id),
+		status TEXT NOT NULL,
+		ordered_at TEXT NOT NULL
+	)`,
+
+	`CREATE TABLE order_items (
+		order_id INTEGER NOT NULL REFERENCES orders(id),
+		product_id INTEGER NOT NULL REFERENCES products(id),
+		quantity INTEGER NOT NULL,
+		PRIMARY KEY (order_id, product_id)
+	)`,
+
+	`INSERT INTO customers VALUES
+		(1, 'Ada Lovelace', 'ada@example.com', 'GB', '2023-01-04'),
+		(2, 'Grace Hopper', 'grace@example.com', 'US', '2023-02-11'),
+		(3, 'Alan Turing', 'alan@example.com', 'GB', '2023-03-19'),
+		(4, 'Katherine Johnson', 'katherine@example.com', 'US',
+		 '2023-05-02'),
+		(5, 'Edsger Dijkstra', 'edsger@example.com', 'NL', '2023-06-23'),
+		(6, 'Barbara Liskov', 'barbara@example.com', 'US', '2023-08-30'),
+		(7, '山田 花子', 'hanako@example.com', 'JP', '2023-09-14')`,
+
+	`INSERT INTO products VALUES
+		(1, 'Mechanical keyboard', 'hardware', 89.00),
+		(2, 'Trackball', 'hardware', 59.50),
+		(3, '27" monitor', 'hardware', 249.99),
+		(4, 'SQL pocket guide', 'books', 14.95),
+		(5, 'Terminal stickers', 'accessories', 4.50),
+		(6, 'Desk mat', 'accessories', 19.00)`,
+
+	`INSERT INTO orders VALUES
+		(1, 1, 'shipped', '2024-01-08'),
+		(2, 2, 'shipped', '2024-01-15'),
+		(3, 1, 'shipped', '2024-02-02'),
+		(4, 3, 'cancelled', '2024-02-20'),
+		(5, 4, 'shipped', '2024-03-05'),
+		(6, 5, 'pending', '2024-03-28'),
+		(7, 6, 'pending', '2024-04-01'),
+		(8, 7, 'shipped', '2024-04-03')`,
+
+	`INSERT INTO order_items VALUES
+		(1, 1, 1), (1, 5, 3),
+		(2, 3, 2),
+		(3, 4, 1), (3, 6, 1),
+		(4, 2, 1),
+		(5, 1, 1), (5, 2, 1), (5, 4, 2),
+		(6, 3, 1),
+		(7, 5, 10),
+		(8, 6, 2), (8, 4, 1)`,
+
+	`CREATE VIEW order_totals AS
+		SELECT o.id, c.name AS customer, o.status,
+		       SUM(i.quantity * p.price) AS total
+		FROM orders o
+		JOIN customers c ON c.id = o.customer_id
+		JOIN order_items i ON i.order_id = o.id
+		JOIN products p ON p.id = i.product_id
+		GROUP BY o.id`,
+}
+
+const demoScript string = `-- Welcome to prequel! This is a throwaway SQLite database with a few
+-- sample tables, so feel free to change anything.
+--
+-- Move the cursor into a statement and press F5 to run it. The statement
+-- under the cursor is highlighted. Press i to type and Escape to go back to
+-- moving around with h, j, k and l, like in vi.
+
+SELECT * FROM customers;
+
+-- Tab switches between the editor and the results. In the results, v shows
+-- the selected value in full and | opens the whole row in your pager.
+
+SELECT c.name, COUNT(o.id) AS orders
+FROM customers c
+LEFT JOIN orders o ON o.customer_id = c.id
+GROUP BY c.id
+ORDER BY orders DESC;
+
+SELECT * FROM order_totals WHERE status = 'shipped';
+
+-- Ctrl+P opens the command palette; try help to see every command, or
+-- messages to see what happened so far. Errors like the one below can be
+-- read in full with the error command.
+
+SELECT * FROM no_such_table;
+
+-- F2 and then a number bookmarks a line, F3 and the number jumps back.
+-- F7 and F8 resize the editor, F9 switches layouts and F6 maximizes the
+-- focused pane.
+
+-- A comment like the next one writes a query's results to a file instead
+-- of showing them:
+-- out: products.csv
+SELECT * FROM products ORDER BY price DESC;
+
+-- Ctrl+C quits. Server tools like processlist need a MySQL connection,
+-- which you can set up in config.json (see the README).
+`
+
+func init() {
+	flag.BoolVar(&demoMode, "demo", false,
+		     "Try prequel on a sample in-memory SQLite database")
+}
+
+func setUpDemo() error {
+	conn, err := db.Conn(appContext)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range demoSchema {
+		_, err := conn.ExecContext(appContext, statement)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	demoConn = conn
+	return nil
+}
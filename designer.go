@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+const designerPrompt string = "Column (name type [null] [pk] [unique] " +
+			      "[index] [ai] [default=x]), blank to finish: "
+
+type designedColumn struct {
+	name          string
+	dataType      string
+	nullable      bool
+	primary       bool
+	unique        bool
+	index         bool
+	autoIncrement bool
+	defaultValue  string
+}
+
+type tableDesign struct {
+	name    string
+	columns []designedColumn
+}
+
+var design tableDesign
+
+func init() {
+	registerCommand(command {
+		name:  "create-table",
+		usage: "<name>",
+		help:  "Design a table column by column",
+		run:   createTableCommand,
+	})
+}
+
+func parseDesignedColumn(spec string) (designedColumn, error) {
+	fields := strings.Fields(spec)
+	if len(fields) < 2 {
+		return designedColumn {}, errors.New(
+			tr("A column needs at least a name and a type"))
+	}
+
+	c := designedColumn {
+		name:     fields[0],
+		dataType: strings.ToUpper(fields[1]),
+	}
+
+	for _, flag := range fields[2:] {
+		switch {
+		case flag == "unsigned":
+			c.dataType += " UNSIGNED"
+		case flag == "null":
+			c.nullable = true
+		case flag == "pk":
+			c.primary = true
+		case flag == "unique":
+			c.unique = true
+		case flag == "index":
+			c.index = true
+		case flag == "ai":
+			c.autoIncrement = true
+		case strings.HasPrefix(flag, "default="):
+			c.defaultValue = strings.TrimPrefix(flag, "default=")
+		default:
+			return c, errors.New(trf("Unknown column option '%s'",
+						 flag))
+		}
+	}
+
+	return c, nil
+}
+
+func (d tableDesign) statement() string {
+	lines := []string {}
+	primary := []string {}
+	keys := []string {}
+
+	for _, c := range d.columns {
+		line := "  " + quoteIdentifier(c.name) + " " + c.dataType
+
+		if c.nullable && !c.primary {
+			line += " NULL"
+		} else {
+			line += " NOT NULL"
+		}
+
+		if c.autoIncrement {
+			line += " AUTO_INCREMENT"
+		}
+
+		if c.defaultValue != "" {
+			line += " DEFAULT " + c.defaultValue
+		}
+
+		lines = append(lines, line)
+
+		name := quoteIdentifier(c.name)
+
+		if c.primary {
+			primary = append(primary, name)
+		}
+
+		if c.unique {
+			keys = append(keys, "  UNIQUE KEY (" + name + ")")
+		}
+
+		if c.index {
+			keys = append(keys, "  KEY (" + name + ")")
+		}
+	}
+
+	if len(primary) > 0 {
+		lines = append(lines, "  PRIMARY KEY (" +
+				      strings.Join(primary, ", ") + ")")
+	}
+
+	lines = append(lines, keys...)
+
+	return "CREATE TABLE " + quoteIdentifier(d.name) + " (\n" +
+	       strings.Join(lines, ",\n") + "\n);\n"
+}
+
+func askForColumn() {
+	askFor(designerPrompt, func(spec string) {
+		if strings.TrimSpace(spec) == "" {
+			finishDesign()
+			return
+		}
+
+		c, err := parseDesignedColumn(spec)
+		if err != nil {
+			askForColumn()
+			status.Text = err.Error() + " - " + status.Text
+			return
+		}
+
+		design.columns = append(design.columns, c)
+		showPopup(trf("Designing %s", design.name), design.statement(), true)
+		askForColumn()
+	})
+}
+
+// Puts the statement in the editor for review rather than running it.
+func finishDesign() {
+	if popupVisible {
+		closePopup()
+	}
+
+	if len(design.columns) == 0 {
+		status.Text = tr("Table design cancelled")
+		return
+	}
+
+	insertAtCursor(design.statement())
+	status.Text = tr("Review the CREATE TABLE statement and run it with F5")
+}
+
+func createTableCommand(args []string) error {
+	if len(args) != 1 {
+		return usageError("create-table")
+	}
+
+	design = tableDesign {
+		name: args[0],
+	}
+
+	askForColumn()
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerCommand(command {
+		name:  "diagram",
+		usage: "<table>",
+		help:  "Draw a table and the tables it's related to by foreign keys",
+		run:   diagramCommand,
+	})
+}
+
+// Draws the tables referencing table above it, and the tables it references
+// below it:
+//
+//	order_items(order_id) --+
+//	                        v
+//	                   +--------+
+//	                   | orders |
+//	                   +--------+
+//	                        +--> customers(id) via customer_id
+func relationshipDiagram(database, table string, keys []foreignKey) string {
+	incoming := []string {}
+	outgoing := []string {}
+
+	for _, k := range keys {
+		if k.outgoing(database, table) {
+			outgoing = append(outgoing, fmt.Sprintf("%s(%s) via %s",
+				qualifiedTable(k.refDatabase, k.refTable),
+				k.refColumns, k.columns))
+		}
+
+		if k.refDatabase == database && k.refTable == table {
+			incoming = append(incoming, fmt.Sprintf("%s(%s)",
+				qualifiedTable(k.database, k.table), k.columns))
+		}
+	}
+
+	name := qualifiedTable(database, table)
+	box := "+" + strings.Repeat("-", displayWidth(name) + 2) + "+"
+
+	// The column the connecting lines run down.
+	mid := len(box) / 2
+	for _, label := range incoming {
+		if displayWidth(label) + 3 > mid {
+			mid = displayWidth(label) + 3
+		}
+	}
+
+	indent := strings.Repeat(" ", mid - len(box) / 2)
+	lines := []string {}
+
+	for _, label := range incoming {
+		lines = append(lines, padRight(label + " ", mid, "-") + "+")
+	}
+
+	if len(incoming) > 0 {
+		lines = append(lines, strings.Repeat(" ", mid) + "v")
+	}
+
+	lines = append(lines, indent + box, indent + "| " + name + " |",
+		       indent + box)
+
+	for _, label := range outgoing {
+		lines = append(lines, strings.Repeat(" ", mid) + "+--> " + label)
+	}
+
+	if len(keys) == 0 {
+		lines = append(lines, "", "No foreign keys reference or are " +
+				      "referenced by " + name)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func diagramCommand(args []string) error {
+	if len(args) != 1 {
+		return usageError("diagram")
+	}
+
+	return showDiagram(splitTableName(args[0]))
+}
+
+func showDiagram(database, table string) error {
+	keys, err := loadForeignKeys(database, table)
+	if err != nil {
+		return err
+	}
+
+	showPopup(trf("Relationships of %s", table),
+		  relationshipDiagram(database, table, keys), false)
+	return nil
+}
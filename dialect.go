@@ -0,0 +1,153 @@
+package main
+
+import (
+	"strings"
+)
+
+type dialect int
+
+const (
+	dialectMySQL dialect = iota
+	dialectPostgres
+	dialectSQLite
+	dialectTSQL
+)
+
+type wordSet map[string]bool
+
+type dialectWords struct {
+	keywords  wordSet
+	types     wordSet
+	functions wordSet
+}
+
+func newWordSet(lists ...string) wordSet {
+	set := wordSet {}
+
+	for _, list := range lists {
+		for _, word := range strings.Fields(list) {
+			set[word] = true
+		}
+	}
+
+	return set
+}
+
+func (s wordSet) contains(word string) bool {
+	return s[strings.ToUpper(word)]
+}
+
+const commonKeywords string = `
+	ADD ALL ALTER AND AS ASC BEGIN BETWEEN BY CASE CHECK COLUMN COMMIT
+	CONSTRAINT CREATE CROSS DEFAULT DELETE DESC DISTINCT DROP ELSE END
+	EXISTS FOREIGN FROM FULL FUNCTION GROUP HAVING IN INDEX INNER INSERT
+	INTO IS JOIN KEY LEFT LIKE LIMIT NOT NULL ON OR ORDER OUTER PRIMARY
+	PROCEDURE REFERENCES RIGHT ROLLBACK SELECT SET TABLE THEN TO
+	TRANSACTION TRIGGER UNION UNIQUE UPDATE USING VALUES VIEW WHEN WHERE
+	WITH`
+
+const commonTypes string = `
+	BIGINT BLOB BOOLEAN CHAR DATE DECIMAL FLOAT INT INTEGER NUMERIC REAL
+	SMALLINT TEXT TIME TIMESTAMP VARCHAR`
+
+const commonFunctions string = `
+	ABS AVG CAST COALESCE COUNT LOWER MAX MIN NULLIF REPLACE ROUND SUM
+	TRIM UPPER`
+
+var dialects = map[dialect]dialectWords {
+	dialectMySQL: {
+		keywords: newWordSet(commonKeywords, `
+			AFTER ANALYZE AUTO_INCREMENT BEFORE BINARY CALL
+			CHANGE CHARACTER CHARSET COLLATE DATABASE DATABASES
+			DELIMITER DESCRIBE DUPLICATE ENGINE EXPLAIN FIELDS
+			FORCE GRANT IF IGNORE INTERVAL KILL LOCK MODIFY
+			OFFSET PARTITION PROCESSLIST REGEXP RENAME REPLACE
+			REVOKE SCHEMA SHOW STATUS STRAIGHT_JOIN TABLES
+			TEMPORARY TRUNCATE UNLOCK UNSIGNED USE VARIABLES
+			ZEROFILL`),
+		types: newWordSet(commonTypes, `
+			BIT DATETIME DOUBLE ENUM JSON LONGBLOB LONGTEXT
+			MEDIUMBLOB MEDIUMINT MEDIUMTEXT TINYBLOB TINYINT
+			TINYTEXT VARBINARY YEAR`),
+		functions: newWordSet(commonFunctions, `
+			BIT_AND BIT_OR CEIL CEILING CHAR_LENGTH CONCAT
+			CONCAT_WS CONVERT CURDATE CURRENT_DATE
+			CURRENT_TIMESTAMP CURTIME DATE DATE_ADD DATE_FORMAT
+			DATE_SUB DATEDIFF DAY DAYOFWEEK FIELD FIND_IN_SET
+			FLOOR FORMAT FROM_UNIXTIME GREATEST GROUP_CONCAT HEX
+			HOUR IF IFNULL INSTR ISNULL JSON_ARRAY JSON_CONTAINS
+			JSON_EXTRACT JSON_OBJECT JSON_SET JSON_UNQUOTE
+			LAST_INSERT_ID LCASE LEAST LEFT LENGTH LOCATE LPAD
+			LTRIM MD5 MINUTE MOD MONTH NOW POSITION POW POWER
+			RAND REVERSE RIGHT RPAD RTRIM SECOND SHA1 SHA2 SIGN
+			SQRT STD STDDEV STR_TO_DATE SUBSTR SUBSTRING
+			SUBSTRING_INDEX SYSDATE TIMESTAMPDIFF TRUNCATE UCASE
+			UNHEX UNIX_TIMESTAMP UTC_TIMESTAMP UUID VARIANCE
+			WEEK YEAR`),
+	},
+	dialectPostgres: {
+		keywords: newWordSet(commonKeywords, `
+			ANALYZE CONFLICT DECLARE DO EXCEPT EXPLAIN ILIKE
+			INTERSECT LANGUAGE LATERAL LOOP NOTHING OFFSET
+			OVER PARTITION PLPGSQL RAISE RETURNING RETURNS
+			SCHEMA SEQUENCE SIMILAR TEMPORARY TRUNCATE VACUUM
+			WINDOW`),
+		types: newWordSet(commonTypes, `
+			BIGSERIAL BYTEA CIDR DOUBLE INET INTERVAL JSON JSONB
+			MONEY PRECISION SERIAL SMALLSERIAL TIMESTAMPTZ TSVECTOR
+			UUID VARYING`),
+		functions: newWordSet(commonFunctions, `
+			AGE ARRAY_AGG ARRAY_LENGTH CEIL CONCAT
+			CURRENT_DATE CURRENT_TIMESTAMP DATE_PART DATE_TRUNC
+			EXTRACT FLOOR GENERATE_SERIES GREATEST INITCAP
+			JSON_AGG JSONB_AGG JSONB_BUILD_OBJECT LEAST LENGTH
+			NEXTVAL NOW POSITION RANDOM REGEXP_REPLACE
+			ROW_NUMBER SPLIT_PART STRING_AGG SUBSTRING
+			TO_CHAR TO_DATE TO_TIMESTAMP`),
+	},
+	dialectSQLite: {
+		keywords: newWordSet(commonKeywords, `
+			ABORT ATTACH AUTOINCREMENT CONFLICT DETACH EXPLAIN
+			FAIL GLOB IGNORE INDEXED OFFSET PRAGMA QUERY RAISE
+			REINDEX REPLACE ROWID TEMP TEMPORARY VACUUM
+			WITHOUT`),
+		types: newWordSet(commonTypes, `
+			DATETIME DOUBLE`),
+		functions: newWordSet(commonFunctions, `
+			CHANGES DATETIME GLOB GROUP_CONCAT HEX IFNULL IIF
+			INSTR JSON JSON_EXTRACT JULIANDAY LAST_INSERT_ROWID
+			LENGTH LIKELIHOOD LTRIM PRINTF QUOTE RANDOM
+			RANDOMBLOB RTRIM STRFTIME SUBSTR TOTAL TYPEOF
+			UNICODE ZEROBLOB`),
+	},
+	dialectTSQL: {
+		keywords: newWordSet(commonKeywords, `
+			APPLY BREAK CATCH CLUSTERED CONTINUE DECLARE EXEC
+			EXECUTE FETCH GO IDENTITY MERGE NOCOUNT NOLOCK
+			NONCLUSTERED OFFSET OUTPUT PIVOT PRINT RETURN ROWS
+			TOP TRAN TRY UNPIVOT WHILE`),
+		types: newWordSet(commonTypes, `
+			BIT DATETIME DATETIME2 DATETIMEOFFSET IMAGE MONEY
+			NCHAR NTEXT NVARCHAR SMALLDATETIME TINYINT
+			UNIQUEIDENTIFIER VARBINARY XML`),
+		functions: newWordSet(commonFunctions, `
+			CEILING CHARINDEX CONCAT CONVERT DATEADD DATEDIFF
+			DATENAME DATEPART FLOOR FORMAT GETDATE GETUTCDATE
+			ISNULL LEN LTRIM NEWID ROW_NUMBER RTRIM
+			SCOPE_IDENTITY STRING_AGG SUBSTRING SYSDATETIME
+			TRY_CAST TRY_CONVERT`),
+	},
+}
+
+func dialectForDriver(driver string) dialect {
+	switch driver {
+	case "postgres", "pgx":
+		return dialectPostgres
+	case "sqlite", "sqlite3":
+		return dialectSQLite
+	case "mssql", "sqlserver":
+		return dialectTSQL
+	}
+
+	return dialectMySQL
+}
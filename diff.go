@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+type resultSet struct {
+	columns []string
+	rows    [][]string
+}
+
+// What diff and compare call the rows only one side has.
+type diffLabels struct {
+	leftOnly  string
+	rightOnly string
+}
+
+func init() {
+	registerCommand(command {
+		name:  "diff",
+		usage: "<table> <table> [key column]",
+		help:  "Diff the rows of two tables by key",
+		run:   diffTablesCommand,
+	})
+
+	registerCommand(command {
+		name:  "diffq",
+		usage: "[key column]",
+		help:  "Diff the current statement's rows against the next one's",
+		run:   diffStatementsCommand,
+	})
+}
+
+// Runs on the editor's connection, like F5, so the statements see its
+// transaction and USE, and through the hooks since they come from the editor.
+func fetchResultSet(query string) (resultSet, error) {
+	conn, err := editorConnection()
+	if err != nil {
+		return resultSet {}, err
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	res, err := queryStatement(ctx, conn, query)
+	if err != nil {
+		return resultSet {}, err
+	}
+	defer res.Close()
+
+	columns, rows, err := scanStrings(res)
+	return resultSet { columns, rows }, err
+}
+
+func columnIndex(columns []string, name string) int {
+	for i, column := range columns {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Compares two result sets row by row, matching rows on the key column
+// (the first column by default). The diff has a leading column saying what
+// happened to each row, and changed values are shown as "old -> new". Where
+// a key isn't unique, identical rows are paired up first and the rest in
+// order, so the sides are compared as multisets.
+func diffResultSets(left, right resultSet, key string,
+		    labels diffLabels) (resultSet, string, error) {
+	if strings.Join(left.columns, ",") != strings.Join(right.columns, ",") {
+		return resultSet {}, "", errors.New(
+			tr("Both sides must return the same columns"))
+	}
+
+	keyIndex := 0
+	if key != "" {
+		keyIndex = columnIndex(left.columns, key)
+		if keyIndex < 0 {
+			return resultSet {}, "", errors.New(
+				trf("No column named %s", key))
+		}
+	}
+
+	// The right side's rows under each key, taken as they're matched.
+	rightRows := map[string][][]string {}
+	for _, row := range right.rows {
+		k := row[keyIndex]
+		rightRows[k] = append(rightRows[k], row)
+	}
+
+	sameRow := func(a, b []string) bool {
+		return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+	}
+
+	// Takes the identical rows out of rightRows first, so they aren't
+	// paired with a changed row that happens to come earlier.
+	same := 0
+	unmatched := [][]string {}
+	for _, row := range left.rows {
+		k := row[keyIndex]
+		found := false
+
+		for j, other := range rightRows[k] {
+			if sameRow(row, other) {
+				rightRows[k] = append(rightRows[k][:j:j],
+						      rightRows[k][j + 1:]...)
+				found = true
+				break
+			}
+		}
+
+		if found {
+			same++
+		} else {
+			unmatched = append(unmatched, row)
+		}
+	}
+
+	diff := resultSet {
+		columns: append([]string {"change"}, left.columns...),
+	}
+	added, removed, changed := 0, 0, 0
+
+	for _, row := range unmatched {
+		k := row[keyIndex]
+
+		if len(rightRows[k]) == 0 {
+			removed++
+			diff.rows = append(diff.rows,
+				append([]string {labels.leftOnly}, row...))
+			continue
+		}
+
+		other := rightRows[k][0]
+		rightRows[k] = rightRows[k][1:]
+
+		cells := make([]string, len(row))
+		for i := range row {
+			cells[i] = row[i]
+			if row[i] != other[i] {
+				cells[i] = row[i] + " -> " + other[i]
+			}
+		}
+
+		changed++
+		diff.rows = append(diff.rows,
+			append([]string {tr("changed")}, cells...))
+	}
+
+	// Whatever is left of the right side had no counterpart.
+	for _, row := range right.rows {
+		k := row[keyIndex]
+		if len(rightRows[k]) > 0 && sameRow(rightRows[k][0], row) {
+			added++
+			diff.rows = append(diff.rows,
+				append([]string {labels.rightOnly}, row...))
+			rightRows[k] = rightRows[k][1:]
+		}
+	}
+
+	summary := trf("%d %s, %d %s, %d changed, %d same", added,
+		       labels.rightOnly, removed, labels.leftOnly, changed,
+		       same)
+
+	return diff, summary, nil
+}
+
+func showDiff(leftQuery, rightQuery, key string) error {
+	left, err := fetchResultSet(leftQuery)
+	if err != nil {
+		return err
+	}
+
+	right, err := fetchResultSet(rightQuery)
+	if err != nil {
+		return err
+	}
+
+	labels := diffLabels {
+		leftOnly:  tr("removed"),
+		rightOnly: tr("added"),
+	}
+
+	diff, summary, err := diffResultSets(left, right, key, labels)
+	if err != nil {
+		return err
+	}
+
+	showResults(diff.columns, diff.rows)
+	showMessage(summary)
+	return nil
+}
+
+func diffTablesCommand(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return usageError("diff")
+	}
+
+	key := ""
+	if len(args) == 3 {
+		key = args[2]
+	}
+
+	return showDiff("SELECT * FROM " + qualifiedTable(splitTableName(args[0])),
+			"SELECT * FROM " + qualifiedTable(splitTableName(args[1])),
+			key)
+}
+
+func diffStatementsCommand(args []string) error {
+	if len(args) > 1 {
+		return usageError("diffq")
+	}
+
+	key := ""
+	if len(args) == 1 {
+		key = args[0]
+	}
+
+	refreshHighlight()
+
+	for i, s := range doc.statements {
+		if s.start != statement.start {
+			continue
+		}
+
+		if i + 1 >= len(doc.statements) {
+			break
+		}
+
+		return showDiff(statementQuery(doc.text, s),
+				statementQuery(doc.text, doc.statements[i + 1]),
+				key)
+	}
+
+	return errors.New(tr("There is no statement after the cursor to " +
+			     "diff against"))
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"sort"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+	"github.com/briansteffens/tui"
+)
+
+// A lexed snapshot of the editor's text. The highlighter, the statement
+// highlighter and runQuery all share it so the buffer is only lexed and
+// split once per change, no matter how many callbacks fire.
+//
+// The editor's own buffer is tui's, which still hands over every character
+// on each change. What this saves is the work on them: edits are compared
+// from where they could have started, and only the text from the edited
+// statement up to where the old tokens and statements line up again is
+// lexed and split.
+type document struct {
+	text       []rune
+	tokens     []token
+	statements []Statement
+	dialect    dialect
+
+	// Nothing before this has changed since the last update, as far as
+	// is known. Zero when anything might have.
+	editedFrom int
+}
+
+var doc document
+
+// Notes that the editor's text may change from offset on.
+func (d *document) noteEdit(offset int) {
+	if offset < 0 {
+		offset = 0
+	}
+
+	if offset < d.editedFrom {
+		d.editedFrom = offset
+	}
+}
+
+// Notes where a key on its way to the editor could change the text. Typing
+// and deleting only touch the text around the cursor; keys that only move
+// it change nothing, and anything else could change it anywhere.
+func (d *document) noteKey(ev escapebox.Event, cursor int) {
+	if ev.Type != termbox.EventKey {
+		return
+	}
+
+	switch ev.Key {
+	case termbox.KeyArrowLeft, termbox.KeyArrowRight, termbox.KeyArrowUp,
+	     termbox.KeyArrowDown, termbox.KeyHome, termbox.KeyEnd,
+	     termbox.KeyPgup, termbox.KeyPgdn:
+		return
+
+	case termbox.KeySpace, termbox.KeyEnter, termbox.KeyTab,
+	     termbox.KeyBackspace, termbox.KeyBackspace2,
+	     termbox.KeyDelete:
+		d.noteEdit(cursor - 1)
+		return
+	}
+
+	if ev.Ch != 0 {
+		d.noteEdit(cursor - 1)
+	} else {
+		d.noteEdit(0)
+	}
+}
+
+// Replaces the editor's text, noting that nothing before from changes.
+func setEditorText(text string, from int) {
+	doc.noteEdit(from)
+	editor.SetText(text)
+}
+
+// Returns where chars first differ from the snapshot, or -1 if they don't.
+func (d *document) firstChange(chars []*tui.Char) int {
+	if d.tokens == nil || d.dialect != sqlDialect {
+		return 0
+	}
+
+	from := d.editedFrom
+	if from > len(d.text) {
+		from = len(d.text)
+	}
+
+	for i := from; i < len(chars); i++ {
+		if i >= len(d.text) || d.text[i] != chars[i].Char {
+			return i
+		}
+	}
+
+	if len(chars) != len(d.text) {
+		return len(chars)
+	}
+
+	return -1
+}
+
+// The index of the statement to lex again from after a change at offset,
+// and the delimiter in effect where it starts. The one before the change
+// is included, since the change may have been to its delimiter.
+func (d *document) restartAt(offset int) (int, string) {
+	k := 0
+	for k < len(d.statements) &&
+	      d.statements[k].start + d.statements[k].length <= offset {
+		k++
+	}
+
+	if k > 0 {
+		k--
+	}
+
+	if k == 0 {
+		return 0, defaultDelimiter
+	}
+
+	return k, d.statements[k - 1].delimiter
+}
+
+// Brings the snapshot up to date with chars, returning false if nothing
+// changed since the last call.
+func (d *document) update(chars []*tui.Char) bool {
+	first := d.firstChange(chars)
+	if first < 0 {
+		d.editedFrom = len(d.text)
+		return false
+	}
+
+	text := make([]rune, len(chars))
+	copy(text, d.text[:first])
+	for i := first; i < len(chars); i++ {
+		text[i] = chars[i].Char
+	}
+
+	// The old tokens and statements can only be picked up again if
+	// they were lexed the same way.
+	reuse := d.tokens != nil && d.dialect == sqlDialect
+
+	// Everything from changeEnd on is the same text as before, moved
+	// along by delta.
+	old := d.text
+	same := 0
+	for same < len(text) - first && same < len(old) - first &&
+	    text[len(text) - 1 - same] == old[len(old) - 1 - same] {
+		same++
+	}
+	changeEnd := len(text) - same
+	delta := len(text) - len(old)
+
+	k, delimiter := d.restartAt(first)
+	start := 0
+	if k > 0 {
+		start = d.statements[k].start
+	}
+
+	// Keeps the tokens before start. A statement can end partway into
+	// whitespace, which is cut there; any other token crossing start means
+	// the text has to be lexed from the top after all.
+	tokens := make([]token, 0, len(d.tokens) + 64)
+	for _, t := range d.tokens {
+		if t.end <= start {
+			tokens = append(tokens, t)
+			continue
+		}
+
+		if t.start < start && t.kind != tokenWhitespace {
+			k, start, delimiter = 0, 0, defaultDelimiter
+			tokens = tokens[:0]
+		} else if t.start < start {
+			t.end = start
+			tokens = append(tokens, t)
+		}
+
+		break
+	}
+	kept := len(tokens)
+
+	// Lexes until a token starts past the change where one started
+	// before, with the same token ahead of it. The lexer would go on
+	// exactly as it did then, so the rest of the old tokens are moved
+	// over instead.
+	l := lexerAfter(text, sqlDialect, tokens)
+	resumed := -1
+
+	for l.pos < len(text) {
+		if reuse && l.pos > changeEnd {
+			j := d.oldTokenAt(l.pos - delta)
+			if j >= 0 && samePrev(text, l.prev, old,
+					      lexerAfter(old, sqlDialect,
+							 d.tokens[:j]).prev) {
+				resumed = j
+				break
+			}
+		}
+
+		tokens = append(tokens, l.next())
+	}
+
+	if resumed >= 0 {
+		for _, t := range d.tokens[resumed:] {
+			t.start += delta
+			t.end += delta
+			tokens = append(tokens, t)
+		}
+	}
+
+	statements := make([]Statement, 0, len(d.statements) + 16)
+	statements = append(statements, d.statements[:k]...)
+	statements = append(statements, d.resplit(text, tokens[kept:], start,
+						  l.pos, delimiter,
+						  resumed >= 0, delta)...)
+
+	d.text = text
+	d.tokens = tokens
+	d.statements = statements
+	d.dialect = sqlDialect
+	d.editedFrom = len(text)
+
+	return true
+}
+
+// Returns the index of the old token starting at offset, or -1 if none
+// does.
+func (d *document) oldTokenAt(offset int) int {
+	j := sort.Search(len(d.tokens), func(i int) bool {
+		return d.tokens[i].start >= offset
+	})
+
+	if j < len(d.tokens) && d.tokens[j].start == offset {
+		return j
+	}
+
+	return -1
+}
+
+// Whether the lexer would treat what comes after a and b the same way, which
+// only hangs on their kind and, for symbols, which one they are.
+func samePrev(text []rune, a token, old []rune, b token) bool {
+	if a.kind != b.kind {
+		return false
+	}
+
+	return a.kind != tokenSymbol || text[a.start] == old[b.start]
+}
+
+// Splits the new text from start, given its tokens from there on. Once the
+// lexer has caught up with the old tokens at resumed, the first old
+// statement starting after that with the same delimiter in effect is where
+// the old statements are picked up again.
+func (d *document) resplit(text []rune, tokens []token, start, resumed int,
+			   delimiter string, reuse bool,
+			   delta int) []Statement {
+	if reuse {
+		m := 0
+		for m < len(d.statements) &&
+		      d.statements[m].start + delta < resumed {
+			m++
+		}
+
+		// The window has to reach past the start of statement m
+		// for it to turn up in the split.
+		end := len(text)
+		if m + 1 < len(d.statements) {
+			end = d.statements[m + 1].start + delta
+		}
+
+		window := splitRange(text, tokens, start, end, delimiter)
+
+		for i := 0; m < len(d.statements) && i < len(window); i++ {
+			before := delimiter
+			if i > 0 {
+				before = window[i - 1].delimiter
+			}
+
+			oldBefore := defaultDelimiter
+			if m > 0 {
+				oldBefore = d.statements[m - 1].delimiter
+			}
+
+			if window[i].start != d.statements[m].start + delta ||
+			   before != oldBefore {
+				continue
+			}
+
+			statements := window[:i]
+			for _, s := range d.statements[m:] {
+				s.start += delta
+				statements = append(statements, s)
+			}
+
+			return statements
+		}
+	}
+
+	return splitRange(text, tokens, start, len(text), delimiter)
+}
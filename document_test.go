@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"math/rand"
+	"testing"
+	"github.com/briansteffens/tui"
+)
+
+var editFragments = []string {
+	"select", "a", "1", ".5", "-", " ", "\n", ";", "'", "\"", "`", "(",
+	")", " -1", "x", "--", "#", "/*", "*/", "$$", "[", "]", "\\", "//",
+	"DELIMITER //\n", "DELIMITER ;\n", "begin select 1; end //\n",
+	"select 'x;y';\n", "-- c;\n",
+}
+
+func charsOf(text []rune) []*tui.Char {
+	chars := make([]*tui.Char, len(text))
+	for i, ch := range text {
+		chars[i] = &tui.Char {Char: ch}
+	}
+
+	return chars
+}
+
+// A statement can end partway into whitespace, leaving it in two tokens
+// that a full lex would have as one.
+func mergeWhitespace(tokens []token) []token {
+	merged := []token {}
+	for _, t := range tokens {
+		last := len(merged) - 1
+		if last >= 0 && t.kind == tokenWhitespace &&
+		   merged[last].kind == tokenWhitespace &&
+		   merged[last].end == t.start {
+			merged[last].end = t.end
+			continue
+		}
+
+		merged = append(merged, t)
+	}
+
+	return merged
+}
+
+// Applies random edits and checks each update against lexing and splitting
+// the whole text.
+func TestDocumentUpdate(t *testing.T) {
+	defer func(d dialect) {
+		sqlDialect = d
+	}(sqlDialect)
+
+	for _, d := range []dialect {dialectMySQL, dialectPostgres,
+				      dialectTSQL} {
+		sqlDialect = d
+		r := rand.New(rand.NewSource(int64(d) + 1))
+		doc = document {}
+		text := []rune {}
+
+		for step := 0; step < 3000; step++ {
+			at := r.Intn(len(text) + 1)
+			removed := 0
+			if at < len(text) && r.Intn(5) == 0 {
+				removed = 1 + r.Intn(len(text) - at)
+				if removed > 8 {
+					removed = 8
+				}
+			}
+
+			inserted := []rune {}
+			if removed == 0 || r.Intn(2) == 0 {
+				fragment := editFragments[r.Intn(
+					len(editFragments))]
+				inserted = []rune(fragment)
+			}
+
+			edited := append([]rune {}, text[:at]...)
+			edited = append(edited, inserted...)
+			text = append(edited, text[at + removed:]...)
+
+			// Only some edits come with a hint, as with the
+			// editor.
+			if r.Intn(2) == 0 {
+				doc.noteEdit(at)
+			} else {
+				doc.noteEdit(0)
+			}
+
+			doc.update(charsOf(text))
+			checkDocument(t, fmt.Sprintf("dialect %d, step %d",
+						     d, step), text, d)
+
+			if t.Failed() {
+				return
+			}
+		}
+	}
+}
+
+func checkDocument(t *testing.T, name string, text []rune, d dialect) {
+	if string(doc.text) != string(text) {
+		t.Errorf("%s: text is %q, want %q", name, string(doc.text),
+			 string(text))
+		return
+	}
+
+	tokens := lex(text, d)
+	if !reflect.DeepEqual(mergeWhitespace(doc.tokens), tokens) {
+		t.Errorf("%s: tokens of %q are %v, want %v", name,
+			 string(text), doc.tokens, tokens)
+	}
+
+	statements := splitStatements(text, tokens)
+	if !reflect.DeepEqual(doc.statements, statements) {
+		t.Errorf("%s: statements of %q are %v, want %v", name,
+			 string(text), doc.statements, statements)
+	}
+}
+
+// Whether a minus sign starts a number depends on the token before it, so
+// the old tokens after an edit can't be picked up again before it.
+func TestDocumentUpdateBeforeMinus(t *testing.T) {
+	defer func(d dialect) {
+		sqlDialect = d
+	}(sqlDialect)
+
+	sqlDialect = dialectMySQL
+	doc = document {}
+
+	for _, text := range []string {
+		"select x -1;\nselect 2;\n",
+		"select ( -1;\nselect 2;\n",
+		"select ) -1;\nselect 2;\n",
+	} {
+		doc.noteEdit(7)
+		doc.update(charsOf([]rune(text)))
+		checkDocument(t, text, []rune(text), dialectMySQL)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"bufio"
+	"errors"
+	"strings"
+	"database/sql"
+)
+
+const dumpBatchSize int = 100
+
+type dumpOptions struct {
+	path     string
+	database string
+	tables   []string
+	schema   bool
+	data     bool
+}
+
+func init() {
+	registerCommand(command {
+		name:  "dump",
+		usage: "[-s|-d] <file> [table]",
+		help:  "Export the database or a table as SQL (-s: schema only, " +
+		       "-d: data only)",
+		run:   dumpCommand,
+	})
+}
+
+func dumpTables(database string) ([]string, error) {
+	rows, err := queryStrings("SELECT table_name " +
+		"FROM information_schema.tables " +
+		"WHERE table_schema = ? AND table_type = 'BASE TABLE' " +
+		"ORDER BY table_name", database)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []string {}
+	for _, row := range rows {
+		tables = append(tables, row[0])
+	}
+
+	return tables, nil
+}
+
+// Formats a value the way mysqldump does: numbers bare, binary data as hex
+// and everything else as a string.
+func dumpLiteral(value sql.NullString, columnType *sql.ColumnType) string {
+	if !value.Valid {
+		return "NULL"
+	}
+
+	name := strings.ToLower(columnType.DatabaseTypeName())
+	name = strings.TrimPrefix(name, "unsigned ")
+
+	switch {
+	case numericTypes[name] && name != "bit":
+		return value.String
+	case name == "bit", strings.Contains(name, "blob"),
+	     strings.Contains(name, "binary"), name == "geometry":
+		if len(value.String) == 0 {
+			return "''"
+		}
+		return fmt.Sprintf("0x%X", value.String)
+	}
+
+	return quoteString(value.String)
+}
+
+func dumpTableSchema(w *bufio.Writer, database, table string) error {
+	rows, err := queryStrings("SHOW CREATE TABLE " +
+				  qualifiedTable(database, table))
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return errors.New(trf("No definition found for %s", table))
+	}
+
+	fmt.Fprintf(w, "--\n-- Table structure for %s\n--\n\n", table)
+	fmt.Fprintf(w, "DROP TABLE IF EXISTS %s;\n", quoteIdentifier(table))
+	fmt.Fprintf(w, "%s;\n\n", rows[0][1])
+	return nil
+}
+
+func dumpTableData(w *bufio.Writer, database, table string) (int, error) {
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	res, err := queryStatement(ctx, db, "SELECT * FROM " +
+				   qualifiedTable(database, table))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Close()
+
+	types, err := res.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+
+	names := []string {}
+	for _, t := range types {
+		names = append(names, quoteIdentifier(t.Name()))
+	}
+
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n",
+			      quoteIdentifier(table), strings.Join(names, ", "))
+
+	values := make([]sql.NullString, len(types))
+	pointers := textScanners(columnKinds(types), values)
+
+	fmt.Fprintf(w, "--\n-- Data for %s\n--\n\n", table)
+
+	count := 0
+	batch := []string {}
+
+	flush := func() {
+		if len(batch) > 0 {
+			fmt.Fprintf(w, "%s%s;\n", prefix, strings.Join(batch, ",\n"))
+			batch = []string {}
+		}
+	}
+
+	for res.Next() {
+		if err := res.Scan(pointers...); err != nil {
+			return count, err
+		}
+
+		literals := []string {}
+		for i, value := range values {
+			literals = append(literals, dumpLiteral(value, types[i]))
+		}
+
+		batch = append(batch, "  (" + strings.Join(literals, ", ") + ")")
+		count++
+
+		if len(batch) == dumpBatchSize {
+			flush()
+		}
+	}
+
+	flush()
+	w.WriteString("\n")
+
+	return count, res.Err()
+}
+
+// Writes to a temporary file first so a failed dump doesn't leave a
+// truncated file behind that looks complete.
+func writeDump(options dumpOptions, progress func(string)) error {
+	file, err := os.Create(options.path + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(options.path + ".tmp")
+
+	w := bufio.NewWriter(file)
+
+	fmt.Fprintf(w, "-- Dump of %s\n-- Generated by prequel on %s\n\n",
+		    options.database, time.Now().Format("2006-01-02 15:04:05"))
+	w.WriteString("SET NAMES utf8mb4;\nSET FOREIGN_KEY_CHECKS = 0;\n\n")
+
+	for i, table := range options.tables {
+		progress(trf("Dumping %s (%d of %d)", table, i + 1,
+			     len(options.tables)))
+
+		if options.schema {
+			err = dumpTableSchema(w, options.database, table)
+			if err != nil {
+				break
+			}
+		}
+
+		if options.data {
+			_, err = dumpTableData(w, options.database, table)
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	w.WriteString("SET FOREIGN_KEY_CHECKS = 1;\n")
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(options.path + ".tmp", options.path)
+}
+
+func dumpCommand(args []string) error {
+	options := dumpOptions {
+		schema: true,
+		data:   true,
+	}
+
+	if len(args) > 0 && (args[0] == "-s" || args[0] == "-d") {
+		options.schema = args[0] == "-s"
+		options.data = args[0] == "-d"
+		args = args[1:]
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return usageError("dump")
+	}
+
+	options.path = args[0]
+	options.database = config.Database
+
+	if len(args) == 2 {
+		database, table := splitTableName(args[1])
+		options.database = database
+		options.tables = []string {table}
+	} else {
+		tables, err := dumpTables(options.database)
+		if err != nil {
+			return err
+		}
+
+		if len(tables) == 0 {
+			return errors.New(tr("There are no tables to dump"))
+		}
+
+		options.tables = tables
+	}
+
+	started := time.Now()
+
+	go func() {
+		defer recoverCrash()
+		err := writeDump(options, func(message string) {
+			post(func() {
+				showProgress(message)
+			})
+		})
+
+		post(func() {
+			clearProgress()
+			notifyIfLong("Dump finished", started)
+
+			if err != nil {
+				showToast(trf("Dump failed: %s", err), true)
+				return
+			}
+
+			showToast(trf("Dumped %d tables to %s",
+				      len(options.tables), options.path),
+				  false)
+		})
+	}()
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"bufio"
+	"regexp"
+	"strings"
+	"database/sql"
+	"encoding/csv"
+	"path/filepath"
+)
+
+var outDirective = regexp.MustCompile(`^--\s*out:\s*(\S+)\s*$`)
+
+// Returns the file named by a "-- out: <file>" comment in the statement, if
+// it has one.
+func outputFile(s Statement) string {
+	for _, t := range doc.tokens {
+		if t.kind != tokenComment || t.start < s.start ||
+		   t.end > s.start + s.length {
+			continue
+		}
+
+		comment := strings.TrimSpace(string(doc.text[t.start:t.end]))
+		if match := outDirective.FindStringSubmatch(comment); match != nil {
+			return match[1]
+		}
+	}
+
+	return ""
+}
+
+// Writes rows to the file as they arrive, as CSV or NDJSON. Other formats
+// (picked by extension like in batch mode) need all the rows first.
+func writeRowsTo(path string, res *sql.Rows) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	count := 0
+
+	switch format {
+	case "csv", "ndjson":
+		columns, err := res.Columns()
+		if err != nil {
+			return 0, err
+		}
+
+		out := csv.NewWriter(w)
+		if format == "csv" {
+			out.Write(columns)
+		}
+
+		types, err := res.ColumnTypes()
+		if err != nil {
+			return 0, err
+		}
+
+		values := make([]sql.NullString, len(columns))
+		pointers := textScanners(columnKinds(types), values)
+
+		for res.Next() {
+			if err := res.Scan(pointers...); err != nil {
+				return count, err
+			}
+
+			if format == "csv" {
+				out.Write(nullsAs([][]sql.NullString {values}, "")[0])
+			} else {
+				fmt.Fprintln(w, jsonObject(columns, values))
+			}
+
+			count++
+		}
+
+		out.Flush()
+		if err := out.Error(); err != nil {
+			return count, err
+		}
+
+	default:
+		write, ok := outputFormats[format]
+		if !ok {
+			write = writeTable
+		}
+
+		columns, rows, err := scanNullStrings(res)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := write(w, columns, rows); err != nil {
+			return 0, err
+		}
+
+		count = len(rows)
+	}
+
+	if err := res.Err(); err != nil {
+		return count, err
+	}
+
+	if err := w.Flush(); err != nil {
+		return count, err
+	}
+
+	// Closing is where a full disk can show up, so it isn't left to the
+	// deferred Close, which ignores it.
+	return count, file.Close()
+}
+
+// Runs the query in the background, leaving the results pane alone. It
+// runs on the editor's connection, like F5, which is kept for it until it's
+// done.
+func exportQuery(query, path string) {
+	conn, err := editorConnection()
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	editorConnBusy = true
+	showProgress(trf("Writing results to %s...", path))
+	showQueryState(true)
+
+	go func() {
+		defer recoverCrash()
+		started := time.Now()
+		count := 0
+
+		ctx, cancel := queryContext()
+		res, err := queryStatement(ctx, conn, query)
+		if err == nil {
+			count, err = writeRowsTo(path, res)
+			res.Close()
+		}
+		cancel()
+
+		post(func() {
+			editorConnBusy = false
+			showQueryState(false)
+			clearProgress()
+			notifyIfLong("Export finished", started)
+
+			if err != nil && connectionLost(err) {
+				resetEditorConnection()
+			}
+
+			if err != nil {
+				showToast(trf("Writing %s failed: %s",
+					      path, err), true)
+				return
+			}
+
+			showToast(trf("Wrote %d rows to %s in %.1fs",
+				      count, path,
+				      time.Since(started).Seconds()),
+				  false)
+		})
+	}()
+}
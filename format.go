@@ -0,0 +1,158 @@
+package main
+
+import (
+	"io"
+	"fmt"
+	"strings"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+)
+
+// Writes the rows of one result set in batch mode.
+type outputFormat func(io.Writer, []string, [][]sql.NullString) error
+
+var outputFormats = map[string]outputFormat {
+	"table":  writeTable,
+	"csv":    writeCSV,
+	"json":   writeJSON,
+	"ndjson": writeNDJSON,
+	"md":     writeMarkdown,
+}
+
+// Prints rows the way the mysql client does:
+//
+//	+----+-------+
+//	| id | name  |
+//	+----+-------+
+//	| 1  | alice |
+//	+----+-------+
+func writeTable(w io.Writer, columns []string,
+		rows [][]sql.NullString) error {
+	strs := nullsAs(rows, "NULL")
+	widths := make([]int, len(columns))
+
+	for i, column := range columns {
+		widths[i] = displayWidth(column)
+
+		for _, row := range strs {
+			if width := displayWidth(row[i]); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	rule := "+"
+	for _, width := range widths {
+		rule += strings.Repeat("-", width + 2) + "+"
+	}
+
+	line := func(values []string) {
+		fmt.Fprint(w, "|")
+		for i, value := range values {
+			fmt.Fprintf(w, " %s |", padRight(value, widths[i], " "))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, rule)
+	line(columns)
+	fmt.Fprintln(w, rule)
+
+	for _, row := range strs {
+		line(row)
+	}
+
+	_, err := fmt.Fprintln(w, rule)
+	return err
+}
+
+// NULLs are written as empty fields.
+func writeCSV(w io.Writer, columns []string, rows [][]sql.NullString) error {
+	out := csv.NewWriter(w)
+	out.Write(columns)
+
+	for _, row := range nullsAs(rows, "") {
+		out.Write(row)
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// Encodes a row as a JSON object with its keys in column order, which a
+// map wouldn't keep.
+func jsonObject(columns []string, row []sql.NullString) string {
+	fields := []string {}
+
+	for i, column := range columns {
+		key, _ := json.Marshal(column)
+		value := []byte("null")
+
+		if row[i].Valid {
+			value, _ = json.Marshal(row[i].String)
+		}
+
+		fields = append(fields, string(key) + ":" + string(value))
+	}
+
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+func writeJSON(w io.Writer, columns []string, rows [][]sql.NullString) error {
+	objects := []string {}
+	for _, row := range rows {
+		objects = append(objects, "  " + jsonObject(columns, row))
+	}
+
+	if len(objects) == 0 {
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "[\n%s\n]\n", strings.Join(objects, ",\n"))
+	return err
+}
+
+func writeNDJSON(w io.Writer, columns []string,
+		 rows [][]sql.NullString) error {
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, jsonObject(columns, row)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", "\\|", -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+func writeMarkdown(w io.Writer, columns []string,
+		   rows [][]sql.NullString) error {
+	line := func(values []string) {
+		cells := []string {}
+		for _, value := range values {
+			cells = append(cells, markdownCell(value))
+		}
+
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+
+	line(columns)
+
+	rules := []string {}
+	for range columns {
+		rules = append(rules, "---")
+	}
+	line(rules)
+
+	for _, row := range nullsAs(rows, "NULL") {
+		line(row)
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
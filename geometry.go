@@ -0,0 +1,213 @@
+package main
+
+import (
+	"math"
+	"errors"
+	"strconv"
+	"strings"
+	"database/sql"
+	"encoding/binary"
+)
+
+// MySQL stores spatial values as a 4-byte SRID followed by well-known
+// binary (WKB).
+const sridLength int = 4
+
+const (
+	wkbPoint = iota + 1
+	wkbLineString
+	wkbPolygon
+	wkbMultiPoint
+	wkbMultiLineString
+	wkbMultiPolygon
+	wkbGeometryCollection
+)
+
+var wkbNames = map[uint32]string {
+	wkbPoint:              "POINT",
+	wkbLineString:         "LINESTRING",
+	wkbPolygon:            "POLYGON",
+	wkbMultiPoint:         "MULTIPOINT",
+	wkbMultiLineString:    "MULTILINESTRING",
+	wkbMultiPolygon:       "MULTIPOLYGON",
+	wkbGeometryCollection: "GEOMETRYCOLLECTION",
+}
+
+var geometryTypes = newWordSet(`GEOMETRY POINT LINESTRING POLYGON MULTIPOINT
+	MULTILINESTRING MULTIPOLYGON GEOMETRYCOLLECTION GEOMCOLLECTION`)
+
+// The raw values of spatial cells shown as WKT, for the cell viewer's hex
+// dump.
+var geometryCells = map[cellPos][]byte {}
+
+type wkbReader struct {
+	data []byte
+	pos  int
+}
+
+// A function rather than a variable so the message is translated with the
+// catalog loaded at the time.
+func errTruncatedWKB() error {
+	return errors.New(tr("Truncated geometry"))
+}
+
+func geometryColumns(types []*sql.ColumnType) []bool {
+	spatial := make([]bool, len(types))
+
+	for i, t := range types {
+		spatial[i] = geometryTypes.contains(t.DatabaseTypeName())
+	}
+
+	return spatial
+}
+
+// Converts a spatial value to well-known text, the way ST_AsText would.
+func geometryText(value []byte) (string, error) {
+	if len(value) < sridLength {
+		return "", errTruncatedWKB()
+	}
+
+	r := wkbReader {
+		data: value[sridLength:],
+	}
+
+	text, err := r.geometry()
+	if err == nil && r.pos != len(r.data) {
+		err = errors.New(tr("Unexpected data after the geometry"))
+	}
+
+	return text, err
+}
+
+func (r *wkbReader) order() (binary.ByteOrder, error) {
+	if r.pos >= len(r.data) {
+		return nil, errTruncatedWKB()
+	}
+
+	b := r.data[r.pos]
+	r.pos++
+
+	switch b {
+	case 0:
+		return binary.BigEndian, nil
+	case 1:
+		return binary.LittleEndian, nil
+	}
+
+	return nil, errors.New(trf("Invalid WKB byte order %d", b))
+}
+
+func (r *wkbReader) uint32(order binary.ByteOrder) (uint32, error) {
+	if r.pos + 4 > len(r.data) {
+		return 0, errTruncatedWKB()
+	}
+
+	value := order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return value, nil
+}
+
+// Reads count prefixed items, joined with commas.
+func (r *wkbReader) list(order binary.ByteOrder,
+			 item func() (string, error)) (string, error) {
+	count, err := r.uint32(order)
+	if err != nil {
+		return "", err
+	}
+
+	// Each item takes at least 4 bytes, so a bigger count is corrupt
+	// rather than worth allocating for.
+	if int(count) > (len(r.data) - r.pos) / 4 {
+		return "", errTruncatedWKB()
+	}
+
+	items := make([]string, 0, count)
+
+	for i := uint32(0); i < count; i++ {
+		text, err := item()
+		if err != nil {
+			return "", err
+		}
+
+		items = append(items, text)
+	}
+
+	return strings.Join(items, ","), nil
+}
+
+func (r *wkbReader) point(order binary.ByteOrder) (string, error) {
+	if r.pos + 16 > len(r.data) {
+		return "", errTruncatedWKB()
+	}
+
+	x := math.Float64frombits(order.Uint64(r.data[r.pos:]))
+	y := math.Float64frombits(order.Uint64(r.data[r.pos + 8:]))
+	r.pos += 16
+
+	return strconv.FormatFloat(x, 'g', -1, 64) + " " +
+	       strconv.FormatFloat(y, 'g', -1, 64), nil
+}
+
+func (r *wkbReader) points(order binary.ByteOrder) (string, error) {
+	return r.list(order, func() (string, error) {
+		return r.point(order)
+	})
+}
+
+// A polygon is a list of rings, each a list of points.
+func (r *wkbReader) rings(order binary.ByteOrder) (string, error) {
+	return r.list(order, func() (string, error) {
+		points, err := r.points(order)
+		return "(" + points + ")", err
+	})
+}
+
+// Reads a geometry with its own header, returning its type and the text
+// between its parentheses.
+func (r *wkbReader) body() (uint32, string, error) {
+	order, err := r.order()
+	if err != nil {
+		return 0, "", err
+	}
+
+	kind, err := r.uint32(order)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var text string
+
+	switch kind {
+	case wkbPoint:
+		text, err = r.point(order)
+	case wkbLineString:
+		text, err = r.points(order)
+	case wkbPolygon:
+		text, err = r.rings(order)
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon:
+		text, err = r.list(order, func() (string, error) {
+			_, inner, err := r.body()
+			return "(" + inner + ")", err
+		})
+	case wkbGeometryCollection:
+		text, err = r.list(order, r.geometry)
+	default:
+		return 0, "", errors.New(trf("Unsupported geometry type %d",
+					     kind))
+	}
+
+	return kind, text, err
+}
+
+func (r *wkbReader) geometry() (string, error) {
+	kind, text, err := r.body()
+	if err != nil {
+		return "", err
+	}
+
+	if text == "" {
+		return wkbNames[kind] + " EMPTY", nil
+	}
+
+	return wkbNames[kind] + "(" + text + ")", nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"time"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/tui"
+)
+
+// Built-in functions are only recognized when immediately followed by an
+// opening paren, since many of them double as keywords (LEFT, IF, ...).
+func isFunctionCall(text []rune, t token) bool {
+	if t.end >= len(text) || text[t.end] != '(' {
+		return false
+	}
+
+	word := string(text[t.start:t.end])
+	return dialects[sqlDialect].functions.contains(word)
+}
+
+func charsToRunes(chars []*tui.Char) []rune {
+	text := make([]rune, len(chars))
+
+	for i, ch := range chars {
+		text[i] = ch.Char
+	}
+
+	return text
+}
+
+func wordColor(text []rune, t token) termbox.Attribute {
+	words := dialects[sqlDialect]
+	word := string(text[t.start:t.end])
+
+	switch {
+	case isFunctionCall(text, t):
+		return theme.Function
+	case words.keywords.contains(word):
+		return theme.Keyword
+	case words.types.contains(word):
+		return theme.Type
+	}
+
+	return theme.Text
+}
+
+// Past this many characters, lexing the whole buffer on every change is
+// slow enough to notice, so highlighting waits for a pause in the typing
+// (or in a paste the terminal replays as keystrokes).
+const largeDocument int = 200000
+
+const highlightDelay time.Duration = 150 * time.Millisecond
+
+var highlightTimer *time.Timer
+
+// Set while the editor has changes that haven't been highlighted yet.
+var highlightStale bool
+
+// Returns true if highlighting the editor should be left for later.
+func deferHighlight(e *tui.EditBox) bool {
+	if e != &editor || len(e.AllChars()) < largeDocument {
+		return false
+	}
+
+	highlightStale = true
+
+	if highlightTimer == nil {
+		highlightTimer = time.AfterFunc(highlightDelay, func() {
+			post(refreshHighlight)
+		})
+	} else {
+		highlightTimer.Reset(highlightDelay)
+	}
+
+	return true
+}
+
+// Catches up on deferred highlighting. Also needed before anything that
+// reads doc, like running the statement under the cursor.
+func refreshHighlight() {
+	if !highlightStale {
+		return
+	}
+
+	highlightStale = false
+	highlight(&editor)
+	lineHighlighter(&editor)
+}
+
+func highlighter(e *tui.EditBox) {
+	if !deferHighlight(e) {
+		highlight(e)
+	}
+}
+
+func highlight(e *tui.EditBox) {
+	chars := e.AllChars()
+
+	// Only the main editor's text is kept in the shared document.
+	text, tokens := doc.text, doc.tokens
+	if e == &editor {
+		doc.update(chars)
+		text, tokens = doc.text, doc.tokens
+	} else {
+		text = charsToRunes(chars)
+		tokens = lex(text, sqlDialect)
+	}
+
+	for _, t := range tokens {
+		color := theme.Text
+
+		switch t.kind {
+		case tokenWord:
+			color = wordColor(text, t)
+		case tokenComment:
+			color = theme.Comment
+		case tokenString:
+			color = theme.String
+		case tokenNumber:
+			color = theme.Number
+		case tokenIdentifier:
+			color = theme.Identifier
+		}
+
+		for i := t.start; i < t.end; i++ {
+			chars[i].Fg = color
+
+			// The editor's background shows the current
+			// statement, which lineHighlighter paints.
+			if e != &editor {
+				chars[i].Bg = theme.Background
+			}
+		}
+	}
+}
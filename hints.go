@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+)
+
+type syntaxHint struct {
+	offset  int
+	message string
+}
+
+var shownHint string
+
+func inStatement(t token, s Statement) bool {
+	return t.end > s.start && t.start < s.start + s.length
+}
+
+// Looks for obvious mistakes in a statement that can be caught without a
+// round trip to the server.
+func statementHints(text []rune, tokens []token, s Statement) []syntaxHint {
+	hints := []syntaxHint {}
+	parens := []int {}
+	var prev *token
+
+	for i := range tokens {
+		t := &tokens[i]
+
+		if !inStatement(*t, s) {
+			continue
+		}
+
+		if t.unterminated {
+			message := "Unterminated quote"
+			if t.kind == tokenComment {
+				message = "Unterminated comment"
+			}
+
+			hints = append(hints, syntaxHint { t.start, message })
+		}
+
+		if t.kind == tokenWhitespace || t.kind == tokenComment {
+			continue
+		}
+
+		trailingComma := prev != nil && prev.kind == tokenSymbol &&
+				 text[prev.start] == ','
+
+		switch {
+		case t.kind == tokenSymbol && text[t.start] == '(':
+			parens = append(parens, t.start)
+
+		case t.kind == tokenSymbol && text[t.start] == ')':
+			if trailingComma {
+				hints = append(hints, syntaxHint { prev.start,
+					"Trailing comma before )" })
+			}
+
+			if len(parens) == 0 {
+				hints = append(hints, syntaxHint { t.start,
+					"Unmatched )" })
+			} else {
+				parens = parens[:len(parens) - 1]
+			}
+
+		case t.kind == tokenWord && trailingComma &&
+		     strings.EqualFold(string(text[t.start:t.end]), "FROM"):
+			hints = append(hints, syntaxHint { prev.start,
+				"Trailing comma before FROM" })
+		}
+
+		prev = t
+	}
+
+	for _, offset := range parens {
+		hints = append(hints, syntaxHint { offset, "Unclosed (" })
+	}
+
+	return hints
+}
+
+// Shows the first hint in the status bar, unless it's already showing
+// something else like a query error. Lint warnings only show when there are
+// no hints, since those are more likely to be why a statement fails.
+func showHints(hints, warnings []syntaxHint) {
+	if status.Text != "" && status.Text != shownHint {
+		return
+	}
+
+	shownHint = ""
+	switch {
+	case len(hints) > 0:
+		shownHint = "Hint: " + hints[0].message
+	case len(warnings) > 0:
+		shownHint = "Warning: " + warnings[0].message
+	}
+
+	status.Text = shownHint
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"time"
+	"bufio"
+	"strings"
+	"path/filepath"
+	"encoding/json"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+// History files are trimmed back to this many statements once they grow
+// to twice as many.
+const maxHistory int = 1000
+
+type historyEntry struct {
+	Query      string    `json:"query"`
+	At         time.Time `json:"at"`
+	Connection string    `json:"connection"`
+}
+
+// The entries listed in the history view, newest first.
+var historyShown []historyEntry
+var historySearch string
+var historyGlobal bool
+
+func init() {
+	registerCommand(command {
+		name:  "history",
+		usage: "[-a] [search]",
+		help:  "Search the statements run on this connection (-a: all)",
+		run:   historyCommand,
+	})
+}
+
+func historyDir() string {
+	return filepath.Join(configDir(), "history")
+}
+
+// Each connection keeps its own history, so statements run against one
+// server don't turn up while working on another.
+func historyPath(conn Connection) string {
+	return filepath.Join(historyDir(), conn.slug() + ".jsonl")
+}
+
+// Reads a history file, oldest first. Lines that can't be parsed are
+// skipped.
+func readHistory(path string) []historyEntry {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	entries := []historyEntry {}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16 << 20)
+
+	for scanner.Scan() {
+		var entry historyEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+func writeHistory(path string, entries []historyEntry) error {
+	var data strings.Builder
+
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		data.Write(line)
+		data.WriteString("\n")
+	}
+
+	return writeFileAtomic(path, []byte(data.String()))
+}
+
+// Appends a statement run from the editor to the connection's history.
+// Failures are only logged: losing history isn't worth interrupting for.
+func recordHistory(query string) {
+	query = strings.TrimSpace(query)
+	query = strings.TrimSpace(strings.TrimSuffix(query, defaultDelimiter))
+	if query == "" || demoMode {
+		return
+	}
+
+	path := historyPath(config.Connection)
+	entry := historyEntry {
+		Query:      query,
+		At:         time.Now(),
+		Connection: config.Connection.String(),
+	}
+
+	err := appendHistory(path, entry)
+	if err != nil {
+		logEvent(levelError, "history not saved", "error", err)
+	}
+}
+
+func appendHistory(path string, entry historyEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+				 0600)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err == nil {
+		_, err = file.Write(append(line, '\n'))
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Rewriting on every statement would be slow for a long history, so
+	// it's only done once it has doubled.
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < int64(maxHistory) * 200 {
+		return err
+	}
+
+	entries := readHistory(path)
+	if len(entries) < maxHistory * 2 {
+		return nil
+	}
+
+	return writeHistory(path, entries[len(entries) - maxHistory:])
+}
+
+// This connection's history, or every connection's, newest first and
+// without repeats of the same statement.
+func loadHistory(global bool) []historyEntry {
+	paths := []string {historyPath(config.Connection)}
+	if global {
+		paths, _ = filepath.Glob(filepath.Join(historyDir(), "*.jsonl"))
+	}
+
+	entries := []historyEntry {}
+	for _, path := range paths {
+		entries = append(entries, readHistory(path)...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.After(entries[j].At)
+	})
+
+	unique := []historyEntry {}
+	seen := map[string]bool {}
+
+	for _, entry := range entries {
+		if !seen[entry.Query] {
+			seen[entry.Query] = true
+			unique = append(unique, entry)
+		}
+	}
+
+	return unique
+}
+
+// Every word of the search must appear in the statement.
+func historyMatches(entry historyEntry, search string) bool {
+	query := strings.ToLower(entry.Query)
+
+	for _, word := range strings.Fields(strings.ToLower(search)) {
+		if !strings.Contains(query, word) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func showHistory(search string, global bool) {
+	historySearch = search
+	historyGlobal = global
+	historyShown = []historyEntry {}
+	rows := [][]string {}
+
+	for _, entry := range loadHistory(global) {
+		if !historyMatches(entry, search) {
+			continue
+		}
+
+		historyShown = append(historyShown, entry)
+
+		row := []string {entry.At.Format("2006-01-02 15:04")}
+		if global {
+			row = append(row, entry.Connection)
+		}
+		rows = append(rows, append(row, abbreviate(entry.Query)))
+	}
+
+	columns := []string {"at", "statement"}
+	if global {
+		columns = []string {"at", "connection", "statement"}
+	}
+
+	showResults(columns, rows)
+	resultsView = "history"
+	container.Focused = &results
+
+	scope := tr("this connection")
+	if global {
+		scope = tr("all connections")
+	}
+
+	status.Text = trf("%d statements from %s (Enter: insert, r: run, " +
+			  "a: this connection/all, /: search)", len(rows), scope)
+}
+
+func historyCommand(args []string) error {
+	global := len(args) > 0 && args[0] == "-a"
+	if global {
+		args = args[1:]
+	}
+
+	showHistory(strings.Join(args, " "), global)
+	return nil
+}
+
+func askForHistorySearch(global bool) {
+	askFor(tr("Search history: "), func(search string) {
+		showHistory(search, global)
+	})
+}
+
+// Ctrl+R searches the history from anywhere.
+func handleHistoryEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey {
+		return false
+	}
+
+	if ev.Key == termbox.KeyCtrlR {
+		askForHistorySearch(historyGlobal && resultsView == "history")
+		return true
+	}
+
+	if container.Focused != &results || resultsView != "history" {
+		return false
+	}
+
+	switch ev.Ch {
+	case 'a':
+		showHistory(historySearch, !historyGlobal)
+		return true
+	case '/':
+		askForHistorySearch(historyGlobal)
+		return true
+	}
+
+	row := results.SelectedRow
+	if row < 0 || row >= len(historyShown) {
+		return false
+	}
+
+	switch {
+	case ev.Key == termbox.KeyEnter:
+		insertQuery(historyShown[row].Query)
+		container.Focused = &editor
+	case ev.Ch == 'r':
+		executeQuery(historyShown[row].Query)
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"errors"
+	"time"
+	"bytes"
+	"regexp"
+	"os/exec"
+	"strings"
+	"sync"
+	"context"
+	"net/http"
+	"database/sql"
+	"encoding/json"
+)
+
+const webhookTimeout time.Duration = 5 * time.Second
+
+var denyPatterns []*regexp.Regexp
+
+// After hooks still running, which batch mode waits for before exiting.
+var runningHooks sync.WaitGroup
+
+// What the After command and webhook are told about a statement.
+type statementReport struct {
+	Connection string  `json:"connection"`
+	Statement  string  `json:"statement"`
+	Error      string  `json:"error,omitempty"`
+	Seconds    float64 `json:"seconds"`
+}
+
+// What statements run on: the pool, one of its connections or a
+// transaction.
+type statementRunner interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result,
+							      error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows,
+							       error)
+}
+
+// Returned when a deny pattern or the before hook stops a statement.
+type refusedError struct {
+	message string
+}
+
+func (e refusedError) Error() string {
+	return e.message
+}
+
+// Deny patterns are case-insensitive.
+func compileHooks(hooks Hooks) error {
+	denyPatterns = nil
+
+	for _, pattern := range hooks.Deny {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return errors.New(trf("Invalid deny pattern '%s': %s",
+					      pattern, err))
+		}
+
+		denyPatterns = append(denyPatterns, re)
+	}
+
+	return nil
+}
+
+// Returns an error if the statement must not be run.
+func beforeStatement(query string) error {
+	for _, re := range denyPatterns {
+		if re.MatchString(query) {
+			return refusedError {fmt.Sprintf(
+				"Refused by the deny pattern '%s'",
+				strings.TrimPrefix(re.String(), "(?i)"))}
+		}
+	}
+
+	before := config.Hooks.Before
+	if len(before) == 0 {
+		return nil
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(before[0], before[1:]...)
+	cmd.Stdin = strings.NewReader(query)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+
+		return refusedError {"Refused by the before hook: " + message}
+	}
+
+	return nil
+}
+
+// Every statement prequel sends on the user's behalf goes through this or
+// queryStatement, so the deny patterns and hooks see all of them.
+func execStatement(ctx context.Context, runner statementRunner, query string,
+		   args ...interface{}) (sql.Result, error) {
+	if err := beforeStatement(query); err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	res, err := runner.ExecContext(ctx, query, args...)
+	afterStatement(query, started, err)
+
+	return res, err
+}
+
+// Like execStatement, for statements that return rows. The after hooks run
+// once the rows start arriving.
+func queryStatement(ctx context.Context, runner statementRunner, query string,
+		    args ...interface{}) (*sql.Rows, error) {
+	if err := beforeStatement(query); err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	res, err := runner.QueryContext(ctx, query, args...)
+	afterStatement(query, started, err)
+
+	return res, err
+}
+
+// Statements are logged in full at the debug level only, since they can
+// contain data.
+func logStatement(query string, started time.Time, err error) {
+	seconds := fmt.Sprintf("%.3f", time.Since(started).Seconds())
+
+	if logging(levelDebug) {
+		query = strings.Join(strings.Fields(query), " ")
+	} else {
+		query = abbreviate(query)
+	}
+
+	if err != nil {
+		logEvent(levelError, "statement failed", "seconds", seconds,
+			 "error", err, "query", query)
+		return
+	}
+
+	logEvent(levelInfo, "statement", "seconds", seconds, "query", query)
+}
+
+// Runs in the background so a slow hook doesn't hold up the next statement.
+// Failures are ignored: the statement has already run.
+func afterStatement(query string, started time.Time, err error) {
+	logStatement(query, started, err)
+
+	hooks := config.Hooks
+	if len(hooks.After) == 0 && hooks.Webhook == "" {
+		return
+	}
+
+	report := statementReport {
+		Connection: config.Connection.String(),
+		Statement:  query,
+		Seconds:    time.Since(started).Seconds(),
+	}
+
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	body, jsonErr := json.Marshal(report)
+	if jsonErr != nil {
+		return
+	}
+
+	runningHooks.Add(1)
+
+	go func() {
+		defer recoverCrash()
+		defer runningHooks.Done()
+
+		if len(hooks.After) > 0 {
+			cmd := exec.Command(hooks.After[0], hooks.After[1:]...)
+			cmd.Stdin = bytes.NewReader(body)
+			cmd.Env = append(os.Environ(),
+					 "PREQUEL_STATEMENT=" + query,
+					 "PREQUEL_ERROR=" + report.Error)
+			cmd.Run()
+		}
+
+		if hooks.Webhook != "" {
+			client := http.Client {
+				Timeout: webhookTimeout,
+			}
+
+			res, err := client.Post(hooks.Webhook, "application/json",
+						bytes.NewReader(body))
+			if err == nil {
+				res.Body.Close()
+			}
+		}
+	}()
+}
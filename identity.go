@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"github.com/briansteffens/tui"
+)
+
+// Shows which server this instance is connected to, at the right end of the
+// status bar, so it isn't overwritten by messages.
+var identity tui.Label
+
+// The connection's name in the terminal title: the profile if one was
+// picked, otherwise user@host:port/database.
+var titleName string
+
+func showIdentity(conn Connection) {
+	identity.Text = conn.String()
+
+	if version, err := queryStrings("SELECT VERSION()"); err == nil {
+		identity.Text += " (" + version[0][0] + ")"
+	}
+
+	identity.Fg = theme.Comment
+	identity.Bg = theme.StatusBg
+
+	titleName = profileName
+	if titleName == "" {
+		titleName = conn.String()
+	}
+
+	showQueryState(false)
+}
+
+// Saves the terminal's own title (xterm's title stack, which most emulators
+// support) so restoreTerminalTitle can put it back.
+func saveTerminalTitle() {
+	fmt.Fprint(os.Stdout, "\x1b[22;0t")
+}
+
+// Inside tmux this also renames the window, so it shows up in the status
+// line (unless the user turned allow-rename off).
+func setTerminalTitle(title string) {
+	fmt.Fprintf(os.Stdout, "\x1b]2;%s\a", title)
+
+	if os.Getenv("TMUX") != "" {
+		fmt.Fprintf(os.Stdout, "\x1bk%s\x1b\\", title)
+	}
+}
+
+// The title is written straight to the terminal, so it changes even while
+// the UI is blocked on a query.
+func showQueryState(running bool) {
+	if running {
+		setTerminalTitle("prequel [running] " + titleName)
+	} else {
+		setTerminalTitle("prequel " + titleName)
+	}
+}
+
+func restoreTerminalTitle() {
+	fmt.Fprint(os.Stdout, "\x1b[23;0t")
+}
+
+func resizeIdentity() {
+	width := displayWidth(identity.Text)
+	if width > container.Width / 2 {
+		width = container.Width / 2
+	}
+
+	identity.Bounds.Top = status.Bounds.Top
+	identity.Bounds.Left = container.Width - width
+	identity.Bounds.Width = width
+	identity.Bounds.Height = 1
+
+	status.Bounds.Width = container.Width - width - 1
+}
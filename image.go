@@ -0,0 +1,289 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"bufio"
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/color"
+	"image/color/palette"
+	"strconv"
+	"strings"
+	"encoding/base64"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"github.com/nsf/termbox-go"
+)
+
+// Assumed when the terminal doesn't report its size in pixels.
+const defaultCellWidth int = 10
+const defaultCellHeight int = 20
+
+// Decoding needs a few bytes per pixel, twice over, so bigger images are
+// refused before they're decoded rather than running out of memory.
+const maxImagePixels int = 4096 * 4096
+
+var imageSignatures = []struct {
+	format string
+	prefix string
+} {
+	{"png", "\x89PNG\r\n\x1a\n"},
+	{"jpeg", "\xff\xd8\xff"},
+	{"gif", "GIF87a"},
+	{"gif", "GIF89a"},
+}
+
+func imageFormat(value string) string {
+	for _, s := range imageSignatures {
+		if strings.HasPrefix(value, s.prefix) {
+			return s.format
+		}
+	}
+
+	// WebP is a RIFF container, which holds other formats too.
+	if len(value) > 12 && value[:4] == "RIFF" && value[8:12] == "WEBP" {
+		return "webp"
+	}
+
+	return ""
+}
+
+// Only iTerm2 can be sent WebP, since it decodes images itself; there's no
+// WebP decoder here to turn one into sixels.
+func canPreview(value, protocol string) bool {
+	switch imageFormat(value) {
+	case "":
+		return false
+	case "webp":
+		return protocol == "iterm"
+	}
+
+	return protocol != "off"
+}
+
+// Uses the images setting, or guesses from the environment which terminals
+// are known to support each protocol.
+func imageProtocol() string {
+	if config.Images != "" && config.Images != "auto" {
+		return config.Images
+	}
+
+	switch {
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app",
+	     os.Getenv("TERM_PROGRAM") == "WezTerm",
+	     os.Getenv("LC_TERMINAL") == "iTerm2":
+		return "iterm"
+	}
+
+	term := os.Getenv("TERM")
+	if strings.Contains(term, "sixel") || strings.HasPrefix(term, "foot") ||
+	   strings.HasPrefix(term, "mlterm") {
+		return "sixel"
+	}
+
+	return "off"
+}
+
+// Scales an image down (never up) to fit in width by height pixels, drawn
+// over black so transparent parts don't come out as palette noise.
+func fitImage(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if w > width {
+		w, h = width, h * width / w
+	}
+
+	if h > height {
+		w, h = w * height / h, height
+	}
+
+	if w < 1 {
+		w = 1
+	}
+
+	if h < 1 {
+		h = 1
+	}
+
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.NewUniform(color.Black), image.Point {},
+		  draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx := bounds.Min.X + x * bounds.Dx() / w
+			sy := bounds.Min.Y + y * bounds.Dy() / h
+			scaled.Set(x, y, flat.At(sx, sy))
+		}
+	}
+
+	return scaled
+}
+
+// Encodes an image as sixels: six rows of pixels at a time, with a pass
+// over each band for every color in it.
+func encodeSixel(img image.Image) string {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, palette.WebSafe)
+	draw.FloydSteinberg.Draw(paletted, bounds, img, bounds.Min)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "\x1bPq\"1;1;%d;%d", w, h)
+
+	for i, c := range paletted.Palette {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", i, r * 100 / 0xffff,
+			    g * 100 / 0xffff, b * 100 / 0xffff)
+	}
+
+	for top := 0; top < h; top += 6 {
+		bands := map[uint8][]byte {}
+		order := []uint8 {}
+
+		for row := 0; row < 6 && top + row < h; row++ {
+			y := bounds.Min.Y + top + row
+
+			for x := 0; x < w; x++ {
+				index := paletted.ColorIndexAt(bounds.Min.X + x, y)
+
+				band, ok := bands[index]
+				if !ok {
+					band = make([]byte, w)
+					bands[index] = band
+					order = append(order, index)
+				}
+
+				band[x] |= 1 << uint(row)
+			}
+		}
+
+		for i, index := range order {
+			if i > 0 {
+				out.WriteString("$")
+			}
+
+			fmt.Fprintf(&out, "#%d", index)
+			writeSixelRuns(&out, bands[index])
+		}
+
+		out.WriteString("-")
+	}
+
+	out.WriteString("\x1b\\")
+	return out.String()
+}
+
+// Repeats of the same column are run-length encoded as !<count><sixel>.
+func writeSixelRuns(out *strings.Builder, band []byte) {
+	for x := 0; x < len(band); {
+		run := 1
+		for x + run < len(band) && band[x + run] == band[x] {
+			run++
+		}
+
+		sixel := string(rune(63 + band[x]))
+		if run > 3 {
+			fmt.Fprintf(out, "!%d%s", run, sixel)
+		} else {
+			out.WriteString(strings.Repeat(sixel, run))
+		}
+
+		x += run
+	}
+}
+
+// iTerm2 decodes the image itself; it only needs to be kept from growing
+// past the space available.
+func encodeITerm(data []byte, columns, rows int) string {
+	size := "width=auto;height=auto"
+
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	cellWidth, cellHeight := cellSize()
+
+	if err != nil || header.Width > columns * cellWidth ||
+	   header.Height > rows * cellHeight {
+		size = fmt.Sprintf("width=%d;height=%d", columns, rows)
+	}
+
+	return tmuxPassthrough(fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;" +
+		"%s;preserveAspectRatio=1:%s\a", len(data), size,
+		base64.StdEncoding.EncodeToString(data)))
+}
+
+func encodeImage(data []byte, protocol string, columns,
+		 rows int) (string, error) {
+	if protocol == "iterm" {
+		return encodeITerm(data, columns, rows), nil
+	}
+
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	if header.Width * header.Height > maxImagePixels {
+		return "", errors.New(trf("The image is too large (%dx%d)",
+					  header.Width, header.Height))
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	cellWidth, cellHeight := cellSize()
+	return encodeSixel(fitImage(img, columns * cellWidth,
+				    rows * cellHeight)), nil
+}
+
+// Draws the image on its own screen until Enter is pressed. Returns true if
+// the hex dump was asked for instead.
+func previewImage(title string, data []byte, protocol string) (bool,
+							       error) {
+	columns, rows := termbox.Size()
+
+	// Leaves room for the title and the prompt.
+	rows -= 4
+	if rows < 1 {
+		rows = 1
+	}
+
+	encoded, err := encodeImage(data, protocol, columns, rows)
+	if err != nil {
+		return false, err
+	}
+
+	answer := ""
+	err = suspend(func() error {
+		fmt.Printf("\x1b[2J\x1b[H%s (%s)\n\n", title,
+			   formatBytes(strconv.Itoa(len(data))))
+		fmt.Print(encoded)
+		fmt.Print(tr("\n\nPress Enter to return to prequel, or h and " +
+			     "Enter for the hex dump"))
+
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.TrimSpace(line)
+		return err
+	})
+
+	return answer == "h", err
+}
+
+func checkImages(setting string) error {
+	switch setting {
+	case "", "auto", "sixel", "iterm", "off":
+		return nil
+	}
+
+	return errors.New(tr("The images setting must be auto, sixel, iterm " +
+			     "or off"))
+}
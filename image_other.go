@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package main
+
+// The size of a character cell in pixels, which only the terminals of the
+// platforms in image_unix.go can be asked for.
+func cellSize() (int, int) {
+	return defaultCellWidth, defaultCellHeight
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// The size of a character cell in pixels, from the terminal if it says.
+func cellSize() (int, int) {
+	var size struct {
+		rows, columns, width, height uint16
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(),
+				       syscall.TIOCGWINSZ,
+				       uintptr(unsafe.Pointer(&size)))
+	if errno != 0 || size.width == 0 || size.height == 0 ||
+	   size.columns == 0 || size.rows == 0 {
+		return defaultCellWidth, defaultCellHeight
+	}
+
+	return int(size.width / size.columns), int(size.height / size.rows)
+}
@@ -0,0 +1,608 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"io/ioutil"
+	"path/filepath"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/go-sql-driver/mysql"
+)
+
+const defaultImportBatchSize int = 500
+const importPreviewRows int = 5
+
+type importColumn struct {
+	name     string
+	dataType string
+}
+
+// An import in progress: records read from a file, and which table column
+// each of the file's fields goes into ("" to skip it).
+type importJob struct {
+	path     string
+	database string
+	table    string
+	columns  []importColumn
+	fields   []string
+	mapping  []string
+	records  [][]interface{}
+	csv      bool
+	dryRun   bool
+}
+
+var importing importJob
+
+func init() {
+	registerCommand(command {
+		name:  "import",
+		usage: "[-n] <file> <table>",
+		help:  "Import a CSV, JSON or NDJSON file (-n: only generate SQL)",
+		run:   importCommand,
+	})
+}
+
+func loadImportColumns(database, table string) ([]importColumn, error) {
+	rows, err := queryStrings("SELECT column_name, data_type " +
+		"FROM information_schema.columns " +
+		"WHERE table_schema = ? AND table_name = ? " +
+		"ORDER BY ordinal_position", database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New(trf("No table named %s", table))
+	}
+
+	columns := []importColumn {}
+	for _, row := range rows {
+		columns = append(columns, importColumn {
+			name:     row[0],
+			dataType: strings.ToLower(row[1]),
+		})
+	}
+
+	return columns, nil
+}
+
+func readCSV(path string) ([]string, [][]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, nil, errors.New(tr("The file is empty"))
+	}
+
+	records := [][]interface{} {}
+
+	for _, line := range lines[1:] {
+		record := make([]interface{}, len(lines[0]))
+		for i := range record {
+			// \N is NULL, like in LOAD DATA.
+			if i < len(line) && line[i] != "\\N" {
+				record[i] = line[i]
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return lines[0], records, nil
+}
+
+// Returns the keys of a JSON object in the order they're written.
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+
+	if t, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, errors.New(tr("Expected an object"))
+	}
+
+	keys := []string {}
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, t.(string))
+	}
+
+	return keys, nil
+}
+
+// Reads either a JSON array of objects or one object per line (NDJSON).
+// The fields are every key seen, in the order they first appear.
+func readJSON(path string) ([]string, [][]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+
+	raws := []json.RawMessage {}
+
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err = decoder.Decode(&raws)
+	} else {
+		for decoder.More() {
+			var raw json.RawMessage
+			if err = decoder.Decode(&raw); err != nil {
+				break
+			}
+
+			raws = append(raws, raw)
+		}
+	}
+
+	objects := []map[string]interface{} {}
+	fields := []string {}
+	seen := map[string]bool {}
+
+	// Maps don't keep the file's key order, so the keys are read off each
+	// object's raw text on their own.
+	for i := 0; err == nil && i < len(raws); i++ {
+		var keys []string
+		if keys, err = objectKeys(raws[i]); err != nil {
+			break
+		}
+
+		object := map[string]interface{} {}
+		decoder := json.NewDecoder(bytes.NewReader(raws[i]))
+		decoder.UseNumber()
+		if err = decoder.Decode(&object); err != nil {
+			break
+		}
+
+		for _, key := range keys {
+			if !seen[key] {
+				seen[key] = true
+				fields = append(fields, key)
+			}
+		}
+
+		objects = append(objects, object)
+	}
+
+	if err != nil {
+		return nil, nil, errors.New(trf("Invalid JSON in %s: %s", path,
+						err))
+	}
+
+	records := [][]interface{} {}
+
+	for _, object := range objects {
+		record := make([]interface{}, len(fields))
+		for i, field := range fields {
+			record[i] = object[field]
+		}
+
+		records = append(records, record)
+	}
+
+	return fields, records, nil
+}
+
+func (j *importJob) column(name string) (importColumn, bool) {
+	for _, c := range j.columns {
+		if strings.EqualFold(c.name, name) {
+			return c, true
+		}
+	}
+
+	return importColumn {}, false
+}
+
+// Maps each field to the table column with the same name, if there is one.
+func (j *importJob) autoMap() {
+	j.mapping = make([]string, len(j.fields))
+
+	for i, field := range j.fields {
+		if c, ok := j.column(strings.TrimSpace(field)); ok {
+			j.mapping[i] = c.name
+		}
+	}
+}
+
+// Applies changes like "name=full_name, notes=-", where - skips the field.
+func (j *importJob) remap(changes string) error {
+	for _, change := range strings.Split(changes, ",") {
+		parts := strings.SplitN(strings.TrimSpace(change), "=", 2)
+		if len(parts) != 2 {
+			return errors.New(trf("Expected field=column, got '%s'",
+					      change))
+		}
+
+		field := columnIndex(j.fields, parts[0])
+		if field < 0 {
+			return errors.New(trf("No field named '%s' in the file",
+					      parts[0]))
+		}
+
+		if parts[1] == "-" {
+			j.mapping[field] = ""
+			continue
+		}
+
+		c, ok := j.column(parts[1])
+		if !ok {
+			return errors.New(trf("No column named '%s' in %s",
+					      parts[1], j.table))
+		}
+
+		j.mapping[field] = c.name
+	}
+
+	return nil
+}
+
+func (j *importJob) mappedColumns() []string {
+	names := []string {}
+	for _, column := range j.mapping {
+		if column != "" {
+			names = append(names, quoteIdentifier(column))
+		}
+	}
+
+	return names
+}
+
+var numericTypes = map[string]bool {
+	"tinyint": true, "smallint": true, "mediumint": true, "int": true,
+	"bigint": true, "decimal": true, "float": true, "double": true,
+	"bit": true, "year": true,
+}
+
+// Converts a value read from a file to a literal suiting the column: JSON
+// booleans become 1 and 0 in numeric columns, numbers (and numeric strings)
+// stay unquoted, and nested objects and arrays are stored as JSON text.
+func literal(value interface{}, c importColumn) string {
+	numeric := numericTypes[c.dataType]
+
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+
+	case bool:
+		if numeric {
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+
+		return quoteString(strconv.FormatBool(v))
+
+	case json.Number:
+		if numeric {
+			return v.String()
+		}
+
+		return quoteString(v.String())
+
+	case string:
+		if _, err := strconv.ParseFloat(v, 64); err == nil && numeric {
+			return v
+		}
+
+		return quoteString(v)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return quoteString(fmt.Sprint(value))
+	}
+
+	return quoteString(string(encoded))
+}
+
+func (j *importJob) insert(records [][]interface{}) string {
+	rows := []string {}
+
+	for _, record := range records {
+		values := []string {}
+		for i, name := range j.mapping {
+			if c, ok := j.column(name); ok && name != "" {
+				values = append(values, literal(record[i], c))
+			}
+		}
+
+		rows = append(rows, "  (" + strings.Join(values, ", ") + ")")
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES\n%s;\n",
+			   qualifiedTable(j.database, j.table),
+			   strings.Join(j.mappedColumns(), ", "),
+			   strings.Join(rows, ",\n"))
+}
+
+func (j *importJob) preview() string {
+	var text strings.Builder
+
+	fmt.Fprintf(&text, "Importing %d records from %s into %s\n\n",
+		    len(j.records), j.path, qualifiedTable(j.database, j.table))
+
+	width := 10
+	for _, field := range j.fields {
+		if displayWidth(field) > width {
+			width = displayWidth(field)
+		}
+	}
+
+	text.WriteString(padRight("Field", width + 2, " ") + "Column\n")
+
+	for i, field := range j.fields {
+		column := j.mapping[i]
+		if column == "" {
+			column = "(skipped)"
+		}
+
+		text.WriteString(padRight(field, width + 2, " ") + column + "\n")
+	}
+
+	sample := j.records
+	if len(sample) > importPreviewRows {
+		sample = sample[:importPreviewRows]
+	}
+
+	if len(sample) > 0 && len(j.mappedColumns()) > 0 {
+		text.WriteString("\n" + j.insert(sample))
+	}
+
+	return text.String()
+}
+
+func askForMapping() {
+	showPopup(tr("Import preview"), importing.preview(), false)
+
+	askFor(tr("Change mapping (field=column, field=-), blank to " +
+		  "continue: "),
+	       func(changes string) {
+		if strings.TrimSpace(changes) == "" {
+			askForBatchSize()
+			return
+		}
+
+		err := importing.remap(changes)
+		askForMapping()
+
+		if err != nil {
+			status.Text = err.Error() + " - " + status.Text
+		}
+	})
+}
+
+func askForBatchSize() {
+	if len(importing.mappedColumns()) == 0 {
+		status.Text = tr("No fields are mapped to columns")
+		return
+	}
+
+	label := fmt.Sprintf("Rows per INSERT [%d]: ", defaultImportBatchSize)
+
+	askFor(label, func(answer string) {
+		size := defaultImportBatchSize
+
+		if strings.TrimSpace(answer) != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(answer))
+			if err != nil || n < 1 {
+				askForBatchSize()
+				status.Text = trf("Not a positive number - %s", label)
+				return
+			}
+
+			size = n
+		}
+
+		if importing.dryRun {
+			importing.generate(size)
+			return
+		}
+
+		if !importing.csv {
+			startImport(size, false)
+			return
+		}
+
+		askFor(tr("Load with insert or load (LOAD DATA LOCAL " +
+			  "INFILE) [insert]: "), func(method string) {
+			startImport(size, strings.TrimSpace(method) == "load")
+		})
+	})
+}
+
+// Puts the INSERTs in the editor instead of running them.
+func (j *importJob) generate(batchSize int) {
+	if popupVisible {
+		closePopup()
+	}
+
+	for start := 0; start < len(j.records); start += batchSize {
+		end := start + batchSize
+		if end > len(j.records) {
+			end = len(j.records)
+		}
+
+		insertAtCursor(j.insert(j.records[start:end]))
+	}
+
+	showMessage(trf("Generated INSERTs for %d records",
+			len(j.records)))
+}
+
+// Runs in the background, reporting progress in the status bar. A failing
+// batch doesn't stop the rest; the failures are listed at the end.
+func startImport(batchSize int, loadData bool) {
+	if popupVisible {
+		closePopup()
+	}
+
+	job := importing
+	showProgress(trf("Importing into %s...", job.table))
+	started := time.Now()
+
+	go func() {
+		defer recoverCrash()
+		var failures []string
+		var err error
+
+		if loadData {
+			err = job.loadData()
+		} else {
+			failures = job.insertBatches(batchSize)
+		}
+
+		post(func() {
+			job.finished(err, failures)
+			notifyIfLong("Import finished", started)
+		})
+	}()
+}
+
+func (j *importJob) insertBatches(batchSize int) []string {
+	failures := []string {}
+
+	for start := 0; start < len(j.records); start += batchSize {
+		end := start + batchSize
+		if end > len(j.records) {
+			end = len(j.records)
+		}
+
+		ctx, cancel := queryContext()
+		_, err := execStatement(ctx, db, j.insert(j.records[start:end]))
+		cancel()
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf(
+				"Records %d-%d: %s", start + 1, end, err))
+		}
+
+		done := end
+		post(func() {
+			showProgress(trf("Importing into %s: %d of %d",
+					 j.table, done, len(j.records)))
+		})
+	}
+
+	return failures
+}
+
+// Lets the server parse the file. Much faster, but the server must have
+// local_infile enabled.
+func (j *importJob) loadData() error {
+	mysql.RegisterLocalFile(j.path)
+	defer mysql.DeregisterLocalFile(j.path)
+
+	targets := []string {}
+	for _, column := range j.mapping {
+		if column == "" {
+			targets = append(targets, "@skipped")
+		} else {
+			targets = append(targets, quoteIdentifier(column))
+		}
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	_, err := execStatement(ctx, db, fmt.Sprintf("LOAD DATA LOCAL INFILE %s INTO TABLE %s " +
+		"FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '\"' " +
+		"LINES TERMINATED BY '\\n' IGNORE 1 LINES (%s)",
+		quoteString(j.path), qualifiedTable(j.database, j.table),
+		strings.Join(targets, ", ")))
+	return err
+}
+
+func (j *importJob) finished(err error, failures []string) {
+	clearProgress()
+
+	if err != nil {
+		showToast(trf("Import failed: %s", err), true)
+		return
+	}
+
+	if len(failures) > 0 {
+		showPopup(tr("Import errors"), strings.Join(failures, "\n"), false)
+		showToast(trf("Import into %s finished with %d failed " +
+			      "batches", j.table, len(failures)), true)
+		return
+	}
+
+	showToast(trf("Imported %d records into %s", len(j.records),
+		      j.table), false)
+}
+
+func importCommand(args []string) error {
+	dryRun := len(args) == 3 && args[0] == "-n"
+	if dryRun {
+		args = args[1:]
+	}
+
+	if len(args) != 2 {
+		return usageError("import")
+	}
+
+	path := args[0]
+	database, table := splitTableName(args[1])
+
+	columns, err := loadImportColumns(database, table)
+	if err != nil {
+		return err
+	}
+
+	read := readCSV
+	extension := strings.ToLower(filepath.Ext(path))
+
+	isJSON := extension == ".json" || extension == ".ndjson" ||
+		  extension == ".jsonl"
+	if isJSON {
+		read = readJSON
+	}
+
+	fields, records, err := read(path)
+	if err != nil {
+		return err
+	}
+
+	importing = importJob {
+		path:     path,
+		database: database,
+		table:    table,
+		columns:  columns,
+		fields:   fields,
+		records:  records,
+		csv:      !isJSON,
+		dryRun:   dryRun,
+	}
+	importing.autoMap()
+
+	askForMapping()
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+type statusSection struct {
+	name string
+	body string
+}
+
+func init() {
+	registerCommand(command {
+		name:  "innodb",
+		usage: "[section]",
+		help:  "Show InnoDB engine status by section",
+		run:   innodbCommand,
+	})
+}
+
+func isRule(line string) bool {
+	return len(line) >= 3 && strings.Trim(line, "-=") == ""
+}
+
+// Splits SHOW ENGINE INNODB STATUS output on its headings, which are
+// underlined and overlined with dashes:
+//
+//	------------
+//	TRANSACTIONS
+//	------------
+func splitInnodbStatus(text string) []statusSection {
+	lines := strings.Split(text, "\n")
+	sections := []statusSection {{name: "HEADER"}}
+	body := []string {}
+
+	flush := func() {
+		last := &sections[len(sections) - 1]
+		last.body = strings.Trim(strings.Join(body, "\n"), "\n")
+		body = []string {}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		if i + 2 < len(lines) && isRule(lines[i]) &&
+		   !isRule(lines[i + 1]) && isRule(lines[i + 2]) {
+			flush()
+			sections = append(sections, statusSection {
+				name: strings.TrimSpace(lines[i + 1]),
+			})
+			i += 2
+			continue
+		}
+
+		body = append(body, lines[i])
+	}
+
+	flush()
+
+	// Drop the empty preamble and END OF INNODB MONITOR OUTPUT.
+	nonEmpty := []statusSection {}
+	for _, section := range sections {
+		if section.body != "" {
+			nonEmpty = append(nonEmpty, section)
+		}
+	}
+
+	return nonEmpty
+}
+
+func innodbCommand(args []string) error {
+	if len(args) > 1 {
+		return usageError("innodb")
+	}
+
+	rows, err := queryStrings("SHOW ENGINE INNODB STATUS")
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 || len(rows[0]) < 3 {
+		return errors.New(tr("No InnoDB status available"))
+	}
+
+	sections := splitInnodbStatus(rows[0][2])
+
+	if len(args) == 1 {
+		filtered := []statusSection {}
+		for _, section := range sections {
+			if strings.Contains(strings.ToLower(section.name),
+					    strings.ToLower(args[0])) {
+				filtered = append(filtered, section)
+			}
+		}
+
+		if len(filtered) == 0 {
+			return errors.New(trf("No InnoDB status section " +
+					      "matching '%s'", args[0]))
+		}
+
+		sections = filtered
+	}
+
+	var text strings.Builder
+	offsets := []int {}
+
+	if len(sections) > 1 {
+		text.WriteString("Sections:\n")
+		for _, section := range sections {
+			text.WriteString("  " + section.name + "\n")
+		}
+		text.WriteString("\n")
+	}
+
+	for _, section := range sections {
+		offsets = append(offsets, len([]rune(text.String())))
+
+		text.WriteString("== " + section.name + " ==\n\n")
+		text.WriteString(section.body + "\n\n")
+	}
+
+	showSectionedPopup("InnoDB status", text.String(), offsets)
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerCommand(command {
+		name:  "indexes",
+		usage: "<table>",
+		help:  "List a table's indexes",
+		run:   indexesCommand,
+	})
+
+	registerCommand(command {
+		name:  "fks",
+		usage: "<table>",
+		help:  "List foreign keys to and from a table",
+		run:   foreignKeysCommand,
+	})
+
+	registerCommand(command {
+		name:  "view",
+		usage: "<view>",
+		help:  "Show a view's definition",
+		run:   viewCommand,
+	})
+}
+
+func showIndexes(database, table string) {
+	rows, err := queryStrings("SELECT index_name, " +
+		"GROUP_CONCAT(column_name ORDER BY seq_in_index), " +
+		"IF(non_unique = 0, 'yes', 'no'), " +
+		"MAX(cardinality), index_type " +
+		"FROM information_schema.statistics " +
+		"WHERE table_schema = ? AND table_name = ? " +
+		"GROUP BY index_name, non_unique, index_type " +
+		"ORDER BY index_name != 'PRIMARY', index_name",
+		database, table)
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	showResults([]string {"index", "columns", "unique", "cardinality",
+			      "type"}, rows)
+	status.Text = trf("%d indexes on %s", len(rows), table)
+}
+
+func indexesCommand(args []string) error {
+	if len(args) != 1 {
+		return usageError("indexes")
+	}
+
+	showIndexes(splitTableName(args[0]))
+	return nil
+}
+
+type foreignKey struct {
+	name        string
+	database    string
+	table       string
+	columns     string
+	refDatabase string
+	refTable    string
+	refColumns  string
+}
+
+// Returns foreign keys both from table to other tables and from other tables
+// to it.
+func loadForeignKeys(database, table string) ([]foreignKey, error) {
+	rows, err := queryStrings("SELECT constraint_name, table_schema, " +
+		"table_name, " +
+		"GROUP_CONCAT(column_name ORDER BY ordinal_position), " +
+		"referenced_table_schema, referenced_table_name, " +
+		"GROUP_CONCAT(referenced_column_name " +
+		"ORDER BY ordinal_position) " +
+		"FROM information_schema.key_column_usage " +
+		"WHERE referenced_table_name IS NOT NULL " +
+		"AND (table_schema = ? AND table_name = ? " +
+		"OR referenced_table_schema = ? " +
+		"AND referenced_table_name = ?) " +
+		"GROUP BY constraint_name, table_schema, table_name, " +
+		"referenced_table_schema, referenced_table_name " +
+		"ORDER BY table_name, constraint_name",
+		database, table, database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []foreignKey {}
+
+	for _, row := range rows {
+		keys = append(keys, foreignKey {
+			name:        row[0],
+			database:    row[1],
+			table:       row[2],
+			columns:     row[3],
+			refDatabase: row[4],
+			refTable:    row[5],
+			refColumns:  row[6],
+		})
+	}
+
+	return keys, nil
+}
+
+func (k foreignKey) outgoing(database, table string) bool {
+	return k.database == database && k.table == table
+}
+
+func showForeignKeys(database, table string) {
+	keys, err := loadForeignKeys(database, table)
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	rows := [][]string {}
+
+	for _, k := range keys {
+		direction := "incoming"
+		if k.outgoing(database, table) {
+			direction = "outgoing"
+		}
+
+		rows = append(rows, []string {
+			direction,
+			k.name,
+			qualifiedTable(k.database, k.table) + "(" + k.columns + ")",
+			qualifiedTable(k.refDatabase, k.refTable) +
+				"(" + k.refColumns + ")",
+		})
+	}
+
+	showResults([]string {"direction", "constraint", "from", "to"}, rows)
+	status.Text = trf("%d foreign keys on %s", len(rows), table)
+}
+
+func foreignKeysCommand(args []string) error {
+	if len(args) != 1 {
+		return usageError("fks")
+	}
+
+	showForeignKeys(splitTableName(args[0]))
+	return nil
+}
+
+var clauseKeywords = newWordSet(`FROM WHERE GROUP ORDER HAVING LIMIT UNION
+	JOIN LEFT RIGHT INNER CROSS STRAIGHT_JOIN`)
+
+var joinModifiers = newWordSet(`LEFT RIGHT INNER CROSS OUTER NATURAL`)
+
+// Puts each top-level clause of a single-line query (like the ones MySQL
+// stores for views) on its own line.
+func breakClauses(query string) string {
+	text := []rune(query)
+	formatted := []rune {}
+	depth := 0
+	var prev string
+
+	for _, t := range lex(text, sqlDialect) {
+		word := string(text[t.start:t.end])
+
+		switch {
+		case t.kind == tokenSymbol && word == "(":
+			depth++
+		case t.kind == tokenSymbol && word == ")":
+			depth--
+		}
+
+		if t.kind == tokenWord && depth == 0 &&
+		   clauseKeywords.contains(word) && !isFunctionCall(text, t) &&
+		   !(strings.EqualFold(word, "JOIN") && joinModifiers.contains(prev)) {
+			formatted = []rune(strings.TrimRight(string(formatted), " "))
+			formatted = append(formatted, '\n')
+		}
+
+		formatted = append(formatted, text[t.start:t.end]...)
+
+		if t.kind != tokenWhitespace && t.kind != tokenComment {
+			prev = word
+		}
+	}
+
+	return strings.TrimSpace(string(formatted))
+}
+
+func showViewDefinition(database, view string) {
+	rows, err := queryStrings("SELECT view_definition " +
+				  "FROM information_schema.views " +
+				  "WHERE table_schema = ? AND table_name = ?",
+				  database, view)
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	if len(rows) == 0 {
+		showError(view + " is not a view")
+		return
+	}
+
+	definition := fmt.Sprintf("CREATE OR REPLACE VIEW %s AS\n%s;\n",
+				  qualifiedTable(database, view),
+				  breakClauses(rows[0][0]))
+
+	showPopup(trf("View %s", view), definition, true)
+}
+
+func viewCommand(args []string) error {
+	if len(args) != 1 {
+		return usageError("view")
+	}
+
+	showViewDefinition(splitTableName(args[0]))
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// Each session also saves the editor to its own journal, named after its
+// pid, which is deleted on a clean exit. A journal whose process is gone
+// means a session crashed or was killed, and it's offered back on the next
+// launch. (The autosave file alone isn't enough: it's shared by every
+// session on the connection, so another one may have overwritten it.)
+var journal autosaver
+
+func journalDir(conn Connection) string {
+	return filepath.Join(configDir(), "journals", conn.slug())
+}
+
+// Starts journaling the editor. Called after the initial text is loaded,
+// so the journal only exists once something has been typed.
+func startJournal(conn Connection) {
+	path := filepath.Join(journalDir(conn),
+			      strconv.Itoa(os.Getpid()) + ".sql")
+	journal = newAutosaver(path)
+}
+
+func (a *autosaver) discard() {
+	a.mutex.Lock()
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.dirty = false
+	a.mutex.Unlock()
+
+	// Wait for a save already in progress.
+	a.writing.Lock()
+	defer a.writing.Unlock()
+
+	if a.path != "" {
+		os.Remove(a.path)
+	}
+}
+
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// Returns the journals left behind by sessions that didn't exit cleanly,
+// oldest first.
+func orphanedJournals(conn Connection) []string {
+	paths, _ := filepath.Glob(filepath.Join(journalDir(conn), "*.sql"))
+	orphans := []string {}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".sql")
+
+		pid, err := strconv.Atoi(name)
+		if err != nil || pid == os.Getpid() || processRunning(pid) {
+			continue
+		}
+
+		orphans = append(orphans, path)
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		a, errA := os.Stat(orphans[i])
+		b, errB := os.Stat(orphans[j])
+		return errA == nil && errB == nil &&
+		       a.ModTime().Before(b.ModTime())
+	})
+
+	return orphans
+}
+
+// Asks about each orphaned journal in turn. Restored text is added to the
+// end of the editor rather than replacing what's there.
+func offerJournals(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	path := paths[0]
+	rest := paths[1:]
+
+	data, err := ioutil.ReadFile(path)
+	text := strings.TrimSpace(string(data))
+
+	info, statErr := os.Stat(path)
+
+	// Nothing to offer if it was unreadable, empty, or is already in the
+	// editor anyway.
+	if err != nil || statErr != nil || text == "" ||
+	   strings.Contains(editor.GetText(), text) {
+		os.Remove(path)
+		offerJournals(rest)
+		return
+	}
+
+	saved := info.ModTime().Format("2006-01-02 15:04")
+
+	showPopup(trf("Unsaved text from %s", saved), string(data), true)
+
+	askFor(trf("A session ended without saving this at %s. Restore it " +
+		   "into the editor? (y/n) ", saved), func(answer string) {
+		if popupVisible {
+			closePopup()
+		}
+
+		if answer == "y" || answer == "yes" {
+			restoreJournal(text, saved)
+		}
+
+		os.Remove(path)
+		offerJournals(rest)
+	})
+}
+
+func restoreJournal(text, saved string) {
+	current := editor.GetText()
+
+	if strings.TrimSpace(current) != "" {
+		text = fmt.Sprintf("%s\n\n-- Restored from %s\n%s\n",
+				   strings.TrimRight(current, "\n"), saved, text)
+	}
+
+	setEditorText(text, 0)
+	showMessage(trf("Restored the text from %s", saved))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+func init() {
+	registerCommand(command {
+		name:  "column",
+		usage: "<name>",
+		help:  "Select the results column best matching name",
+		run:   columnCommand,
+	})
+}
+
+// Scores how well pattern matches a column name, ignoring case: an exact
+// match beats a prefix, a prefix beats a substring, and a substring beats
+// letters picked out in order. Returns -1 if the letters aren't all there.
+func fuzzyScore(pattern, name string) int {
+	p := []rune(strings.ToLower(pattern))
+	n := []rune(strings.ToLower(name))
+
+	switch index := strings.Index(string(n), string(p)); {
+	case string(n) == string(p):
+		return 3000
+	case index == 0:
+		return 2000 - len(n)
+	case index > 0:
+		return 1000 - index
+	}
+
+	score := 500
+	matched := 0
+
+	for i, r := range n {
+		if matched == len(p) {
+			break
+		}
+
+		if r != p[matched] {
+			score--
+			continue
+		}
+
+		// Letters starting a word, like the i of customer_id, count
+		// for more than ones in the middle of it.
+		if i == 0 || !unicode.IsLetter(n[i - 1]) {
+			score += 10
+		}
+
+		matched++
+	}
+
+	if matched < len(p) {
+		return -1
+	}
+
+	if score < 1 {
+		return 1
+	}
+
+	return score
+}
+
+// Returns the results column best matching pattern, or -1 if none do. Ties
+// go to the leftmost column.
+func bestColumn(pattern string) int {
+	best, bestScore := -1, 0
+
+	for i := range results.Columns {
+		score := fuzzyScore(pattern, columnName(i))
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return best
+}
+
+// Moves the selection a column at a time, as h and l do, so the grid
+// scrolls to keep it in view.
+func selectColumn(column int) {
+	key := 'l'
+	if column < results.SelectedColumn {
+		key = 'h'
+	}
+
+	for results.SelectedColumn != column {
+		before := results.SelectedColumn
+		results.HandleEvent(escapebox.Event {
+			Type: termbox.EventKey,
+			Ch:   key,
+		})
+
+		if results.SelectedColumn == before {
+			results.SelectedColumn = column
+			break
+		}
+	}
+}
+
+func jumpToColumn(pattern string) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil
+	}
+
+	if len(results.Columns) == 0 {
+		return errors.New(tr("There are no results"))
+	}
+
+	column := bestColumn(pattern)
+	if column < 0 {
+		return errors.New(trf("No column matches '%s'", pattern))
+	}
+
+	selectColumn(column)
+	showMessage(columnName(column))
+	return nil
+}
+
+func columnCommand(args []string) error {
+	if len(args) == 0 {
+		return usageError("column")
+	}
+
+	return jumpToColumn(strings.Join(args, " "))
+}
+
+func handleColumnJumpEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey || ev.Ch != 'c' ||
+	   len(results.Columns) == 0 {
+		return false
+	}
+
+	askFor(tr("Column: "), func(pattern string) {
+		if err := jumpToColumn(pattern); err != nil {
+			showError(err.Error())
+		}
+	})
+
+	return true
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+// The keys prequel doesn't use itself, which the keys setting can bind to
+// palette commands (including saved queries, by name).
+var bindableKeys = map[string]termbox.Key {
+	"F1":  termbox.KeyF1,
+	"F10": termbox.KeyF10,
+	"F11": termbox.KeyF11,
+	"F12": termbox.KeyF12,
+}
+
+func checkKeys(keys map[string]string) error {
+	for name := range keys {
+		if _, ok := bindableKeys[strings.ToUpper(name)]; !ok {
+			return errors.New(trf("Can't bind %s, only F1, F10, " +
+					      "F11 and F12 are free", name))
+		}
+	}
+
+	return nil
+}
+
+func handleKeyBindingEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey || ev.Ch != 0 {
+		return false
+	}
+
+	for name, line := range config.Keys {
+		if bindableKeys[strings.ToUpper(name)] == ev.Key {
+			runCommand(line)
+			return true
+		}
+	}
+
+	return false
+}
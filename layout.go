@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"encoding/json"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+	"github.com/briansteffens/tui"
+)
+
+const defaultSplit float64 = 0.5
+const splitStep float64 = 0.05
+
+const (
+	layoutEditorTop  string = "editor-top"
+	layoutResultsTop string = "results-top"
+	layoutEditorLeft string = "editor-left"
+)
+
+var layouts = []string {layoutEditorTop, layoutResultsTop, layoutEditorLeft}
+
+// UI settings changed from within prequel, kept between sessions.
+type uiState struct {
+	// The share of the height (or width, side by side) given to the
+	// editor.
+	Split  float64 `json:"split"`
+	Layout string  `json:"layout"`
+
+	// The profile last connected with, and per connection (by slug) where
+	// its session left off.
+	Profile  string                  `json:"profile"`
+	Sessions map[string]sessionState `json:"sessions"`
+}
+
+var state = uiState {
+	Split:  defaultSplit,
+	Layout: layoutEditorTop,
+}
+
+func init() {
+	registerCommand(command {
+		name:  "layout",
+		usage: "[editor-top|results-top|editor-left]",
+		help:  "Arrange the editor and results, or cycle through layouts",
+		run:   layoutCommand,
+	})
+}
+
+// Whether the divider between the editor and results is being dragged.
+var draggingSplit bool
+
+// The editor or results when maximized over the other, like tmux's zoom.
+var zoomed tui.Control
+
+func statePath() string {
+	return filepath.Join(configDir(), "state.json")
+}
+
+func loadState() {
+	data, err := ioutil.ReadFile(statePath())
+	if err != nil {
+		return
+	}
+
+	json.Unmarshal(data, &state)
+
+	if state.Split <= 0 || state.Split >= 1 {
+		state.Split = defaultSplit
+	}
+
+	if layoutIndex(state.Layout) < 0 {
+		state.Layout = layoutEditorTop
+	}
+}
+
+func layoutIndex(name string) int {
+	for i, layout := range layouts {
+		if layout == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func saveState() {
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return
+	}
+
+	if err := writeFileAtomic(statePath(), data); err != nil {
+		showError(trf("Saving the layout failed: %s", err))
+	}
+}
+
+// The editor's share of size lines or columns, leaving at least minimum
+// for the results.
+func editorSize(size, minimum int) int {
+	editorSize := int(float64(size) * state.Split + 0.5)
+
+	if editorSize > size - minimum {
+		editorSize = size - minimum
+	}
+
+	if editorSize < 1 {
+		editorSize = 1
+	}
+
+	return editorSize
+}
+
+// Places the editor and results in the area right of the sidebar and above
+// the status bar. Side by side, a blank column separates them.
+func layoutPanes(left, width, height int) {
+	editor.Bounds.Left = left
+	editor.Bounds.Top = 0
+	editor.Bounds.Width = width
+	editor.Bounds.Height = height
+
+	results.Bounds = editor.Bounds
+
+	if zoomed != nil {
+		return
+	}
+
+	switch state.Layout {
+	case layoutEditorLeft:
+		editor.Bounds.Width = editorSize(width, 11)
+		results.Bounds.Left = left + editor.Bounds.Width + 1
+		results.Bounds.Width = width - editor.Bounds.Width - 1
+
+	case layoutResultsTop:
+		// Two lines for the results' header and a row.
+		editor.Bounds.Height = editorSize(height, 2)
+		results.Bounds.Height = height - editor.Bounds.Height
+		editor.Bounds.Top = results.Bounds.Height
+
+	default:
+		editor.Bounds.Height = editorSize(height, 2)
+		results.Bounds.Top = editor.Bounds.Height
+		results.Bounds.Height = height - editor.Bounds.Height
+	}
+
+	if pinnedVisible {
+		layoutPinned()
+	}
+}
+
+// Maximizes the focused pane, or puts things back if one already is.
+func toggleZoom() {
+	switch {
+	case zoomed != nil:
+		zoomed = nil
+	case container.Focused == &editor:
+		zoomed = &editor
+	case container.Focused == &results:
+		zoomed = &results
+	default:
+		return
+	}
+
+	updateControls()
+	resizeHandler()
+}
+
+func setLayout(name string) {
+	state.Layout = name
+	resizeHandler()
+	saveState()
+}
+
+func layoutCommand(args []string) error {
+	switch len(args) {
+	case 0:
+		next := (layoutIndex(state.Layout) + 1) % len(layouts)
+		setLayout(layouts[next])
+		status.Text = trf("Layout: %s", state.Layout)
+		return nil
+
+	case 1:
+		if layoutIndex(args[0]) < 0 {
+			return errors.New(trf("No layout named '%s'", args[0]))
+		}
+
+		setLayout(args[0])
+		return nil
+	}
+
+	return usageError("layout")
+}
+
+// Whether the mouse is on the border between the editor and results: the
+// results header when the editor is on top, or the blank column between
+// them side by side.
+func onDivider(x, y int) bool {
+	if zoomed != nil {
+		return false
+	}
+
+	switch state.Layout {
+	case layoutEditorTop:
+		return y == results.Bounds.Top && x >= editor.Bounds.Left
+	case layoutEditorLeft:
+		return x == results.Bounds.Left - 1
+	}
+
+	return false
+}
+
+func splitAt(x, y int) float64 {
+	if state.Layout == layoutEditorLeft {
+		width := container.Width - editor.Bounds.Left
+		return float64(x - editor.Bounds.Left) / float64(width)
+	}
+
+	return float64(y) / float64(container.Height - 1)
+}
+
+// Keeps some of each pane visible.
+func setSplit(split float64) {
+	if split < 0.05 {
+		split = 0.05
+	}
+
+	if split > 0.95 {
+		split = 0.95
+	}
+
+	state.Split = split
+	resizeHandler()
+}
+
+// F7 and F8 shrink and grow the editor, F9 switches to the next layout. The
+// divider can also be dragged with the mouse.
+func handleSplitEvent(ev escapebox.Event) bool {
+	switch {
+	case ev.Type == termbox.EventKey && ev.Key == termbox.KeyF7:
+		setSplit(state.Split - splitStep)
+		saveState()
+		return true
+
+	case ev.Type == termbox.EventKey && ev.Key == termbox.KeyF8:
+		setSplit(state.Split + splitStep)
+		saveState()
+		return true
+
+	case ev.Type == termbox.EventKey && ev.Key == termbox.KeyF9:
+		layoutCommand(nil)
+		return true
+
+	case ev.Type == termbox.EventKey && ev.Key == termbox.KeyF6:
+		toggleZoom()
+		return true
+
+	case ev.Type != termbox.EventMouse:
+		return false
+
+	case ev.Key == termbox.MouseLeft && !draggingSplit:
+		if !onDivider(ev.MouseX, ev.MouseY) {
+			return false
+		}
+
+		draggingSplit = true
+		return true
+
+	case ev.Key == termbox.MouseLeft:
+		setSplit(splitAt(ev.MouseX, ev.MouseY))
+		return true
+
+	case ev.Key == termbox.MouseRelease && draggingSplit:
+		draggingSplit = false
+		saveState()
+		return true
+	}
+
+	return false
+}
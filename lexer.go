@@ -0,0 +1,339 @@
+package main
+
+type tokenKind int
+
+const (
+	tokenWhitespace tokenKind = iota
+	tokenWord
+	tokenString
+	tokenIdentifier
+	tokenComment
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind         tokenKind
+	start        int
+	end          int
+	unterminated bool
+}
+
+func isSpace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func isWordChar(ch rune) bool {
+	return ch == '_' || ch == '$' ||
+	       ch >= 'a' && ch <= 'z' ||
+	       ch >= 'A' && ch <= 'Z' ||
+	       ch >= '0' && ch <= '9' ||
+	       ch > 127
+}
+
+// MySQL only treats -- as a comment when followed by whitespace or EOF, but
+// also allows # comments.
+func isLineComment(text []rune, i int, d dialect) bool {
+	if d == dialectMySQL && text[i] == '#' {
+		return true
+	}
+
+	if text[i] != '-' || i + 1 >= len(text) || text[i + 1] != '-' {
+		return false
+	}
+
+	return d != dialectMySQL || i + 2 >= len(text) || isSpace(text[i + 2])
+}
+
+func isDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || ch >= 'a' && ch <= 'f' || ch >= 'A' && ch <= 'F'
+}
+
+func digitsFrom(text []rune, i int, valid func(rune) bool) int {
+	for i < len(text) && valid(text[i]) {
+		i++
+	}
+
+	return i
+}
+
+// Returns the offset just past the numeric literal starting at i, or i if
+// there isn't one. Handles integers, decimals, exponents and 0x hex.
+func scanNumber(text []rune, i int) int {
+	start := i
+
+	if i + 2 < len(text) && text[i] == '0' &&
+	   (text[i + 1] == 'x' || text[i + 1] == 'X') && isHexDigit(text[i + 2]) {
+		i = digitsFrom(text, i + 2, isHexDigit)
+	} else {
+		i = digitsFrom(text, i, isDigit)
+
+		if i < len(text) && text[i] == '.' {
+			i = digitsFrom(text, i + 1, isDigit)
+		}
+
+		// A lone dot isn't a number.
+		if i - start == 1 && text[start] == '.' {
+			return start
+		}
+
+		if i + 1 < len(text) && (text[i] == 'e' || text[i] == 'E') {
+			exponent := i + 1
+			if text[exponent] == '+' || text[exponent] == '-' {
+				exponent++
+			}
+
+			if exponent < len(text) && isDigit(text[exponent]) {
+				i = digitsFrom(text, exponent, isDigit)
+			}
+		}
+	}
+
+	// Identifiers can start with digits (e.g. 1st_place).
+	if i < len(text) && isWordChar(text[i]) {
+		return start
+	}
+
+	return i
+}
+
+// A minus sign belongs to a number when it isn't a binary operator, judging
+// by what comes right before it.
+func isNegativeNumber(text []rune, i int, prev token) bool {
+	if text[i] != '-' || i + 1 >= len(text) ||
+	   !isDigit(text[i + 1]) && text[i + 1] != '.' {
+		return false
+	}
+
+	if i > 0 && isWordChar(text[i - 1]) {
+		return false
+	}
+
+	switch prev.kind {
+	case tokenNumber, tokenString, tokenIdentifier:
+		return false
+	case tokenSymbol:
+		return text[prev.start] != ')'
+	}
+
+	return true
+}
+
+// Returns the offset just past a T-SQL [bracketed] identifier starting at i,
+// or -1 if it is never closed. Closing brackets are escaped by doubling them.
+func skipBracketed(text []rune, i int) int {
+	for i++; i < len(text); i++ {
+		if text[i] != ']' {
+			continue
+		}
+
+		if i + 1 < len(text) && text[i + 1] == ']' {
+			i++
+			continue
+		}
+
+		return i + 1
+	}
+
+	return -1
+}
+
+// Returns the offset just past a Postgres dollar-quote tag ($$ or $tag$)
+// starting at i, or i if there isn't one.
+func scanDollarTag(text []rune, i int) int {
+	if text[i] != '$' {
+		return i
+	}
+
+	for j := i + 1; j < len(text); j++ {
+		switch {
+		case text[j] == '$':
+			return j + 1
+		case isDigit(text[j]) && j == i + 1:
+			return i
+		case !isWordChar(text[j]):
+			return i
+		}
+	}
+
+	return i
+}
+
+// Returns the offset just past the dollar-quoted string starting at i, or -1
+// if it is never closed.
+func skipDollarQuoted(text []rune, i int) int {
+	end := scanDollarTag(text, i)
+	tag := string(text[i:end])
+
+	for j := end; j + len(tag) <= len(text); j++ {
+		if string(text[j:j + len(tag)]) == tag {
+			return j + len(tag)
+		}
+	}
+
+	return -1
+}
+
+func isCommentEnd(text []rune, i int) bool {
+	return text[i] == '*' && i + 1 < len(text) && text[i + 1] == '/'
+}
+
+// Lexes text a token at a time, so lexing can pick up partway through.
+type lexer struct {
+	text    []rune
+	dialect dialect
+	pos     int
+
+	// The last token that wasn't whitespace or a comment.
+	prev token
+}
+
+func lex(text []rune, d dialect) []token {
+	l := lexer {
+		text:    text,
+		dialect: d,
+	}
+
+	tokens := []token {}
+	for l.pos < len(text) {
+		tokens = append(tokens, l.next())
+	}
+
+	return tokens
+}
+
+// Returns a lexer that carries on after tokens, which must cover text up to
+// where the lexer starts.
+func lexerAfter(text []rune, d dialect, tokens []token) *lexer {
+	l := &lexer {
+		text:    text,
+		dialect: d,
+	}
+
+	if len(tokens) > 0 {
+		l.pos = tokens[len(tokens) - 1].end
+	}
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].kind != tokenWhitespace &&
+		   tokens[i].kind != tokenComment {
+			l.prev = tokens[i]
+			break
+		}
+	}
+
+	return l
+}
+
+// Lexes the token at pos and moves past it.
+func (l *lexer) next() token {
+	text, d, i := l.text, l.dialect, l.pos
+	start := i
+	kind := tokenSymbol
+
+	switch {
+	case isSpace(text[i]):
+		kind = tokenWhitespace
+		for i < len(text) && isSpace(text[i]) {
+			i++
+		}
+
+	case isLineComment(text, i, d):
+		kind = tokenComment
+		for i < len(text) && text[i] != '\n' {
+			i++
+		}
+
+	case text[i] == '/' && i + 1 < len(text) && text[i + 1] == '*':
+		kind = tokenComment
+		i += 2
+		for i < len(text) && !isCommentEnd(text, i) {
+			i++
+		}
+		if i < len(text) {
+			i += 2
+		} else {
+			i = -1
+		}
+
+	case text[i] == '\'' || text[i] == '"':
+		kind = tokenString
+		i = skipQuoted(text, i)
+
+	case d == dialectPostgres && scanDollarTag(text, i) > i:
+		kind = tokenString
+		i = skipDollarQuoted(text, i)
+
+	case text[i] == '`':
+		kind = tokenIdentifier
+		i = skipQuoted(text, i)
+
+	case d == dialectTSQL && text[i] == '[':
+		kind = tokenIdentifier
+		i = skipBracketed(text, i)
+
+	case isNegativeNumber(text, i, l.prev) &&
+	     scanNumber(text, i + 1) > i + 1:
+		kind = tokenNumber
+		i = scanNumber(text, i + 1)
+
+	case scanNumber(text, i) > i:
+		kind = tokenNumber
+		i = scanNumber(text, i)
+
+	case isWordChar(text[i]):
+		kind = tokenWord
+		for i < len(text) && isWordChar(text[i]) {
+			i++
+		}
+
+	default:
+		i++
+	}
+
+	unterminated := i < 0
+	if unterminated {
+		i = len(text)
+	}
+
+	t := token {
+		kind:         kind,
+		start:        start,
+		end:          i,
+		unterminated: unterminated,
+	}
+
+	if kind != tokenWhitespace && kind != tokenComment {
+		l.prev = t
+	}
+
+	l.pos = i
+	return t
+}
+
+// Returns the offset just past the quoted section starting at i, or -1 if it
+// is never closed. Quotes can be escaped by doubling them, or with a
+// backslash except in backticks.
+func skipQuoted(text []rune, i int) int {
+	quote := text[i]
+	i++
+
+	for i < len(text) {
+		switch {
+		case text[i] == '\\' && quote != '`':
+			i += 2
+		case text[i] == quote && i + 1 < len(text) && text[i + 1] == quote:
+			i += 2
+		case text[i] == quote:
+			return i + 1
+		default:
+			i++
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"errors"
+	"strings"
+	"io/ioutil"
+	"path/filepath"
+	"encoding/json"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+// A query saved to the library under a name, to be found again by name,
+// tag or description.
+type libraryEntry struct {
+	Name        string   `json:"name"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Query       string   `json:"query"`
+}
+
+// The entries listed in the library view, in the order shown.
+var libraryShown []libraryEntry
+var libraryFilter string
+
+func init() {
+	registerCommand(command {
+		name:  "save",
+		usage: "<name> [tag...]",
+		help:  "Save the current statement to the query library",
+		run:   saveCommand,
+	})
+
+	registerCommand(command {
+		name:  "library",
+		usage: "[search]",
+		help:  "Browse and search the query library",
+		run:   libraryCommand,
+	})
+}
+
+// A team can share a library by pointing the library setting at a file in
+// a shared repository.
+func libraryPath() string {
+	if config.Library != "" {
+		return config.Library
+	}
+
+	return filepath.Join(configDir(), "library.json")
+}
+
+func loadLibrary() ([]libraryEntry, error) {
+	data, err := ioutil.ReadFile(libraryPath())
+	if os.IsNotExist(err) {
+		return []libraryEntry {}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []libraryEntry {}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.New(trf("Invalid JSON in %s: %s",
+					   libraryPath(), err))
+	}
+
+	return entries, nil
+}
+
+func saveLibrary(entries []libraryEntry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Name) <
+		       strings.ToLower(entries[j].Name)
+	})
+
+	data, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(libraryPath(), append(data, '\n'))
+}
+
+func findEntry(entries []libraryEntry, name string) int {
+	for i, entry := range entries {
+		if strings.EqualFold(entry.Name, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Matches every word of the search against the name, tags and description.
+// A word starting with # only matches a tag.
+func (e libraryEntry) matches(search string) bool {
+	for _, word := range strings.Fields(strings.ToLower(search)) {
+		if strings.HasPrefix(word, "#") {
+			if !containsFold(e.Tags, word[1:]) {
+				return false
+			}
+			continue
+		}
+
+		text := strings.ToLower(e.Name + " " + strings.Join(e.Tags, " ") +
+					" " + e.Description)
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func showLibrary(search string) error {
+	entries, err := loadLibrary()
+	if err != nil {
+		return err
+	}
+
+	libraryFilter = search
+	libraryShown = []libraryEntry {}
+	rows := [][]string {}
+
+	for _, entry := range entries {
+		if !entry.matches(search) {
+			continue
+		}
+
+		libraryShown = append(libraryShown, entry)
+		rows = append(rows, []string {entry.Name,
+			strings.Join(entry.Tags, " "), entry.Description,
+			abbreviate(entry.Query)})
+	}
+
+	showResults([]string {"name", "tags", "description", "query"}, rows)
+	resultsView = "library"
+
+	status.Text = tr("Enter: insert, r: run, d: delete, /: search")
+	return nil
+}
+
+func libraryCommand(args []string) error {
+	return showLibrary(strings.Join(args, " "))
+}
+
+// The statement under the cursor, without its delimiter.
+func currentQuery() string {
+	refreshHighlight()
+
+	query := strings.TrimSpace(statementQuery(doc.text, statement))
+	return strings.TrimSpace(strings.TrimSuffix(query, defaultDelimiter))
+}
+
+func saveCommand(args []string) error {
+	if len(args) < 1 {
+		return usageError("save")
+	}
+
+	query := currentQuery()
+	if query == "" {
+		return errors.New(tr("There is no statement under the cursor"))
+	}
+
+	entries, err := loadLibrary()
+	if err != nil {
+		return err
+	}
+
+	entry := libraryEntry {
+		Name:  args[0],
+		Tags:  args[1:],
+		Query: query,
+	}
+
+	existing := findEntry(entries, entry.Name)
+
+	save := func() {
+		askFor(tr("Description: "), func(description string) {
+			entry.Description = strings.TrimSpace(description)
+
+			if existing >= 0 {
+				entries[existing] = entry
+			} else {
+				entries = append(entries, entry)
+			}
+
+			if err := saveLibrary(entries); err != nil {
+				showError(err.Error())
+				return
+			}
+
+			showMessage(trf("Saved %s to the library", entry.Name))
+		})
+	}
+
+	if existing >= 0 {
+		confirm(trf("Replace %s in the library?", entry.Name), save)
+	} else {
+		save()
+	}
+
+	return nil
+}
+
+// Statements are inserted on a line of their own.
+func insertQuery(query string) {
+	text := []rune(editor.GetText())
+	cursor := editor.GetCursor()
+
+	prefix := ""
+	if cursor > 0 && cursor <= len(text) && text[cursor - 1] != '\n' {
+		prefix = "\n"
+	}
+
+	insertAtCursor(prefix + query + defaultDelimiter + "\n")
+}
+
+// Queries can be templates, whose placeholders are filled in from args in
+// order, with any left over asked for.
+func runEntry(entry libraryEntry, args []string) error {
+	params := placeholderParams([]string {entry.Query})
+	if len(args) > len(params) {
+		return errors.New(trf("%s takes %d values", entry.Name,
+				      len(params)))
+	}
+
+	values := map[string]string {}
+	for i, arg := range args {
+		values[params[i].name] = arg
+	}
+
+	if err := validatePlaceholders(params, values); err != nil {
+		return err
+	}
+
+	askForPlaceholders(params, values, func() {
+		executeQuery(fillPlaceholders(entry.Query, values,
+					      paramKinds(params)))
+	})
+
+	return nil
+}
+
+// Saved queries can be run from the palette by name, like commands.
+func runSavedQuery(name string, args []string) error {
+	entries, err := loadLibrary()
+	if err != nil {
+		return err
+	}
+
+	i := findEntry(entries, name)
+	if i < 0 {
+		return errors.New(trf("Unknown command '%s', try 'help'", name))
+	}
+
+	return runEntry(entries[i], args)
+}
+
+func deleteEntry(name string) {
+	confirm(trf("Delete %s from the library?", name), func() {
+		entries, err := loadLibrary()
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+
+		i := findEntry(entries, name)
+		if i < 0 {
+			return
+		}
+
+		err = saveLibrary(append(entries[:i], entries[i + 1:]...))
+		if err != nil {
+			showError(err.Error())
+			return
+		}
+
+		if err := showLibrary(libraryFilter); err != nil {
+			showError(err.Error())
+		}
+	})
+}
+
+func handleLibraryEvent(ev escapebox.Event) bool {
+	if resultsView != "library" || ev.Type != termbox.EventKey {
+		return false
+	}
+
+	if ev.Ch == '/' {
+		askFor(tr("Search the library: "), func(search string) {
+			if err := showLibrary(search); err != nil {
+				showError(err.Error())
+			}
+		})
+		return true
+	}
+
+	row := results.SelectedRow
+	if row < 0 || row >= len(libraryShown) {
+		return false
+	}
+
+	entry := libraryShown[row]
+
+	switch {
+	case ev.Key == termbox.KeyEnter:
+		insertQuery(entry.Query)
+		container.Focused = &editor
+
+	case ev.Ch == 'r':
+		if err := runEntry(entry, nil); err != nil {
+			showError(err.Error())
+		}
+
+	case ev.Ch == 'd':
+		deleteEntry(entry.Name)
+
+	default:
+		return false
+	}
+
+	return true
+}
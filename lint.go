@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Checks for statements that are valid but probably not what was meant, or
+// slow. Each can be turned off in the lint setting.
+type lintRule struct {
+	name  string
+	check func(text []rune, words []token) []syntaxHint
+}
+
+var lintRules = []lintRule {
+	{"select_star", lintSelectStar},
+	{"cross_join", lintCrossJoin},
+	{"leading_wildcard", lintLeadingWildcard},
+	{"delete_without_limit", lintDeleteWithoutLimit},
+}
+
+func checkLint(rules map[string]bool) error {
+	for name := range rules {
+		found := false
+		for _, rule := range lintRules {
+			found = found || rule.name == name
+		}
+
+		if !found {
+			return errors.New(trf("Unknown lint rule '%s'", name))
+		}
+	}
+
+	return nil
+}
+
+// Rules are on unless the lint setting turns them off.
+func lintEnabled(name string) bool {
+	enabled, ok := config.Lint[name]
+	return !ok || enabled
+}
+
+func lintWarnings(text []rune, tokens []token, s Statement) []syntaxHint {
+	words := []token {}
+	for _, t := range tokens {
+		if inStatement(t, s) && t.kind != tokenWhitespace &&
+		   t.kind != tokenComment {
+			words = append(words, t)
+		}
+	}
+
+	warnings := []syntaxHint {}
+	for _, rule := range lintRules {
+		if lintEnabled(rule.name) {
+			warnings = append(warnings, rule.check(text, words)...)
+		}
+	}
+
+	return warnings
+}
+
+func isWord(text []rune, t token, word string) bool {
+	return t.kind == tokenWord &&
+	       strings.EqualFold(string(text[t.start:t.end]), word)
+}
+
+func isSymbol(text []rune, t token, symbol rune) bool {
+	return t.kind == tokenSymbol && text[t.start] == symbol
+}
+
+// Only on connections marked as production, where pulling every column of a
+// big table hurts more.
+func lintSelectStar(text []rune, words []token) []syntaxHint {
+	if !config.Production {
+		return nil
+	}
+
+	warnings := []syntaxHint {}
+
+	for i := 0; i + 1 < len(words); i++ {
+		if !isWord(text, words[i], "SELECT") {
+			continue
+		}
+
+		next := i + 1
+		if isWord(text, words[next], "DISTINCT") ||
+		   isWord(text, words[next], "ALL") {
+			next++
+		}
+
+		if next < len(words) && isSymbol(text, words[next], '*') {
+			warnings = append(warnings, syntaxHint {
+				words[next].start,
+				tr("SELECT * on a production connection"),
+			})
+		}
+	}
+
+	return warnings
+}
+
+// The FROM clause of one SELECT, tracked per level of parentheses so
+// subqueries are judged on their own.
+type fromClause struct {
+	inFrom bool
+	comma  int
+	where  bool
+}
+
+// A comma between tables with no WHERE at all joins every row of one with
+// every row of the other.
+func lintCrossJoin(text []rune, words []token) []syntaxHint {
+	warnings := []syntaxHint {}
+	levels := []fromClause {{comma: -1}}
+
+	finish := func(c fromClause) {
+		if c.comma >= 0 && !c.where {
+			warnings = append(warnings, syntaxHint { c.comma,
+				tr("Implicit cross join: comma in FROM, " +
+				   "no WHERE") })
+		}
+	}
+
+	for _, t := range words {
+		top := &levels[len(levels) - 1]
+
+		switch {
+		case isSymbol(text, t, '('):
+			levels = append(levels, fromClause {comma: -1})
+
+		case isSymbol(text, t, ')'):
+			if len(levels) > 1 {
+				finish(*top)
+				levels = levels[:len(levels) - 1]
+			}
+
+		case isSymbol(text, t, ','):
+			if top.inFrom && top.comma < 0 {
+				top.comma = t.start
+			}
+
+		case t.kind != tokenWord:
+
+		default:
+			switch strings.ToUpper(string(text[t.start:t.end])) {
+			case "FROM":
+				top.inFrom = true
+			case "WHERE":
+				top.inFrom = false
+				top.where = true
+			case "GROUP", "ORDER", "LIMIT", "HAVING", "WINDOW":
+				top.inFrom = false
+			case "UNION", "EXCEPT", "INTERSECT":
+				finish(*top)
+				*top = fromClause {comma: -1}
+			}
+		}
+	}
+
+	for _, c := range levels {
+		finish(c)
+	}
+
+	return warnings
+}
+
+// A pattern starting with % has to be compared against every row, since no
+// index can narrow it down.
+func lintLeadingWildcard(text []rune, words []token) []syntaxHint {
+	warnings := []syntaxHint {}
+
+	for i := 0; i + 1 < len(words); i++ {
+		pattern := words[i + 1]
+
+		like := isWord(text, words[i], "LIKE") ||
+			isWord(text, words[i], "ILIKE")
+
+		if !like || pattern.kind != tokenString ||
+		   pattern.end - pattern.start < 2 {
+			continue
+		}
+
+		if text[pattern.start + 1] == '%' {
+			warnings = append(warnings, syntaxHint { pattern.start,
+				tr("LIKE with a leading % can't " +
+				   "use an index") })
+		}
+	}
+
+	return warnings
+}
+
+// MySQL can cap a DELETE with LIMIT, which stops a mistaken WHERE from
+// emptying the table. Other databases can't, so there only a DELETE with no
+// WHERE is flagged.
+func lintDeleteWithoutLimit(text []rune, words []token) []syntaxHint {
+	if len(words) == 0 || !isWord(text, words[0], "DELETE") {
+		return nil
+	}
+
+	limit := "LIMIT"
+	message := tr("DELETE without LIMIT")
+	if sqlDialect != dialectMySQL {
+		limit = "WHERE"
+		message = tr("DELETE without WHERE")
+	}
+
+	depth := 0
+	for _, t := range words {
+		switch {
+		case isSymbol(text, t, '('):
+			depth++
+		case isSymbol(text, t, ')'):
+			depth--
+		case depth == 0 && isWord(text, t, limit):
+			return nil
+		}
+	}
+
+	return []syntaxHint {{ words[0].start, message }}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"time"
+	"sync/atomic"
+	"github.com/nsf/termbox-go"
+)
+
+const liveRefreshInterval time.Duration = 2 * time.Second
+
+// A results view that keeps refreshing itself in the background until
+// something else is shown in the results pane.
+type liveView struct {
+	name  string
+	fetch func() (resultSet, error)
+	stop  chan bool
+}
+
+var live *liveView
+
+var pending = make(chan func(), 64)
+
+// Set while the main loop has been woken but hasn't run the pending
+// functions yet. Every wakeup redraws the whole UI, so a burst of posts
+// from a background job only wakes it once.
+var wakeupPending int32
+
+// Runs f on the UI goroutine, waking up the main loop to do it.
+func post(f func()) {
+	pending <- f
+
+	if atomic.CompareAndSwapInt32(&wakeupPending, 0, 1) {
+		termbox.Interrupt()
+	}
+}
+
+func runPending() {
+	// Cleared first, so anything posted from here on wakes the loop again.
+	atomic.StoreInt32(&wakeupPending, 0)
+
+	for {
+		select {
+		case f := <-pending:
+			f()
+		default:
+			return
+		}
+	}
+}
+
+func startLiveView(name string, fetch func() (resultSet, error)) error {
+	set, err := fetch()
+	if err != nil {
+		return err
+	}
+
+	showResults(set.columns, set.rows)
+
+	view := &liveView {
+		name:  name,
+		fetch: fetch,
+		stop:  make(chan bool),
+	}
+	live = view
+
+	go view.run()
+	return nil
+}
+
+func (v *liveView) run() {
+	defer recoverCrash()
+	ticker := time.NewTicker(liveRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.stop:
+			return
+		case <-ticker.C:
+		}
+
+		set, err := v.fetch()
+
+		post(func() {
+			// It may have been stopped while fetching.
+			if live != v {
+				return
+			}
+
+			if err != nil {
+				showQueryError("", err)
+				return
+			}
+
+			setResults(set.columns, set.rows)
+		})
+	}
+}
+
+func stopLiveView() {
+	if live == nil {
+		return
+	}
+
+	close(live.stop)
+	live = nil
+}
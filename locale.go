@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"errors"
+	"strings"
+	"io/ioutil"
+	"path/filepath"
+	"encoding/json"
+)
+
+// Translations of the UI's text, keyed by the English text. Format strings
+// are translated before their values are filled in, so a translation has to
+// keep the same verbs (%s, %d...) in the same order.
+var catalog map[string]string
+
+// The locale setting if there is one, otherwise the usual environment
+// variables, e.g. de_DE.UTF-8.
+func currentLocale(setting string) (string, bool) {
+	if setting != "" {
+		return setting, true
+	}
+
+	for _, name := range []string {"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value, false
+		}
+	}
+
+	return "", false
+}
+
+func loadCatalog(setting string) error {
+	next, err := readCatalog(setting)
+	if err == nil {
+		catalog = next
+	}
+
+	return err
+}
+
+// Reads locales/<locale>.json from the config directory, falling back from
+// de_DE to de. English needs no catalog (nil), and neither does a locale
+// that only came from the environment and has no translation.
+func readCatalog(setting string) (map[string]string, error) {
+	locale, configured := currentLocale(setting)
+	locale = strings.SplitN(locale, ".", 2)[0]
+
+	if locale == "" || locale == "C" || locale == "POSIX" ||
+	   strings.HasPrefix(locale, "en") {
+		return nil, nil
+	}
+
+	names := []string {locale}
+	if i := strings.Index(locale, "_"); i > 0 {
+		names = append(names, locale[:i])
+	}
+
+	for _, name := range names {
+		path := filepath.Join(configDir(), "locales", name + ".json")
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		translations := map[string]string {}
+		if err := json.Unmarshal(data, &translations); err != nil {
+			return nil, errors.New(trf("Invalid translation file " +
+						   "%s: %s", path, err))
+		}
+
+		return translations, nil
+	}
+
+	if configured {
+		return nil, errors.New(trf("No translation found for locale " +
+					   "'%s'", locale))
+	}
+
+	return nil, nil
+}
+
+func tr(text string) string {
+	if translated, ok := catalog[text]; ok && translated != "" {
+		return translated
+	}
+
+	return text
+}
+
+func trf(format string, args ...interface{}) string {
+	return fmt.Sprintf(tr(format), args...)
+}
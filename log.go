@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"errors"
+	"flag"
+	"sync"
+	"time"
+	"strconv"
+	"strings"
+	"path/filepath"
+)
+
+type logLevel int
+
+const (
+	levelOff logLevel = iota
+	levelError
+	levelInfo
+	levelDebug
+)
+
+var logLevels = map[string]logLevel {
+	"off":   levelOff,
+	"error": levelError,
+	"info":  levelInfo,
+	"debug": levelDebug,
+}
+
+var levelNames = map[logLevel]string {
+	levelError: "ERROR",
+	levelInfo:  "INFO",
+	levelDebug: "DEBUG",
+}
+
+// Writes one line per event to log_file, with the details as key=value
+// pairs so the log can be grepped and parsed.
+var logger struct {
+	mutex sync.Mutex
+	level logLevel
+	file  *os.File
+}
+
+var debugMode bool
+
+func init() {
+	flag.BoolVar(&debugMode, "debug", false,
+		     "Log everything, including each key press, to log_file")
+}
+
+func defaultLogFile() string {
+	return filepath.Join(configDir(), "prequel.log")
+}
+
+func openLog(level, path string) error {
+	l, file, err := prepareLog(level, path)
+	if err == nil {
+		setLog(l, file)
+	}
+
+	return err
+}
+
+// Opens the log file for a level without switching to it yet, so a reload
+// can fail without leaving logging off. The file is nil at level off.
+func prepareLog(level, path string) (logLevel, *os.File, error) {
+	if level == "" {
+		level = "off"
+	}
+
+	l, ok := logLevels[level]
+	if !ok {
+		return levelOff, nil, errors.New(trf("Invalid log_level '%s'",
+						     level))
+	}
+
+	if debugMode {
+		l = levelDebug
+	}
+
+	if l == levelOff {
+		return l, nil, nil
+	}
+
+	if path == "" {
+		path = defaultLogFile()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return levelOff, nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+				 0600)
+	if err != nil {
+		return levelOff, nil, err
+	}
+
+	return l, file, nil
+}
+
+// Switches to a log opened by prepareLog, closing the one before.
+func setLog(level logLevel, file *os.File) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.file != nil {
+		logger.file.Close()
+	}
+
+	logger.level = level
+	logger.file = file
+}
+
+func closeLog() {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.file != nil {
+		logger.file.Close()
+		logger.file = nil
+	}
+
+	logger.level = levelOff
+}
+
+// Checked before building anything expensive to log.
+func logging(level logLevel) bool {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	return level <= logger.level
+}
+
+func logValue(value interface{}) string {
+	text := fmt.Sprint(value)
+
+	if text == "" || strings.ContainsAny(text, " \t\r\n\"=") {
+		return strconv.Quote(text)
+	}
+
+	return text
+}
+
+// Logs an event with pairs of keys and values, e.g.
+// logEvent(levelInfo, "connected", "to", conn).
+func logEvent(level logLevel, event string, pairs ...interface{}) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if level > logger.level || logger.file == nil {
+		return
+	}
+
+	var line strings.Builder
+
+	fmt.Fprintf(&line, "%s %-5s %s",
+		    time.Now().Format("2006-01-02T15:04:05.000"),
+		    levelNames[level], event)
+
+	for i := 0; i + 1 < len(pairs); i += 2 {
+		fmt.Fprintf(&line, " %s=%s", pairs[i], logValue(pairs[i + 1]))
+	}
+
+	line.WriteString("\n")
+	logger.file.WriteString(line.String())
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Every placeholder across the macro's steps, in order of first use.
+func (m Macro) params() []placeholder {
+	return placeholderParams(m.Steps)
+}
+
+func isCommandStep(step string) bool {
+	return strings.HasPrefix(strings.TrimSpace(step), "!")
+}
+
+// Runs the steps in order, stopping at the first failure. The last SQL
+// statement's results are shown.
+func runMacro(m Macro, values map[string]string) error {
+	last := -1
+	for i, step := range m.Steps {
+		if !isCommandStep(step) {
+			last = i
+		}
+	}
+
+	kinds := paramKinds(m.params())
+
+	steps := make([]string, len(m.Steps))
+	for i, step := range m.Steps {
+		steps[i] = fillPlaceholders(step, values, kinds)
+	}
+
+	return runMacroSteps(steps, 0, last)
+}
+
+// Runs steps from the first one given. A statement that confirmScan has to
+// ask about carries on with the rest once it's answered, so failures from
+// then on are shown rather than returned.
+func runMacroSteps(steps []string, first, last int) error {
+	for i := first; i < len(steps); i++ {
+		step := steps[i]
+
+		switch {
+		case isCommandStep(step):
+			line := strings.TrimPrefix(strings.TrimSpace(step), "!")
+			if err := execCommand(line); err != nil {
+				return err
+			}
+
+		case i == last:
+			executeQuery(step)
+
+		default:
+			i := i
+			confirmScan(step, func() {
+				err := runMacroStep(step, i)
+				if err == nil {
+					err = runMacroSteps(steps, i + 1, last)
+				}
+
+				if err != nil {
+					showError(err.Error())
+				}
+			})
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func runMacroStep(step string, i int) error {
+	conn, err := editorConnection()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := queryContext()
+	_, err = execStatement(ctx, conn, step)
+	cancel()
+
+	if err != nil {
+		return errors.New(trf("Step %d: %s", i + 1, err))
+	}
+
+	trackTransaction(step)
+	return nil
+}
+
+// Asks for any parameters not given as arguments, one at a time.
+func askForParams(m Macro, params []placeholder, values map[string]string) {
+	askForPlaceholders(params, values, func() {
+		if err := runMacro(m, values); err != nil {
+			showError(err.Error())
+		}
+	})
+}
+
+func registerMacros(macros map[string]Macro) {
+	for name, m := range macros {
+		name, m := name, m
+		params := m.params()
+
+		usage := ""
+		for _, param := range params {
+			usage += "[" + param.name + "] "
+		}
+
+		help := m.Help
+		if help == "" {
+			help = "Macro"
+		}
+
+		registerUserCommand(command {
+			name:  name,
+			usage: strings.TrimSpace(usage),
+			help:  help,
+			run: func(args []string) error {
+				if len(args) > len(params) {
+					return usageError(name)
+				}
+
+				values := map[string]string {}
+				for i, arg := range args {
+					values[params[i].name] = arg
+				}
+
+				err := validatePlaceholders(params, values)
+				if err != nil {
+					return err
+				}
+
+				askForParams(m, params, values)
+				return nil
+			},
+		})
+	}
+}
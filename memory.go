@@ -0,0 +1,32 @@
+package main
+
+// In megabytes.
+const defaultMaxMemory int = 512
+
+// Bytes of results to buffer for the results view before giving up, or 0
+// for no limit.
+func memoryBudget() int64 {
+	if config.MaxMemory <= 0 {
+		return 0
+	}
+
+	return int64(config.MaxMemory) << 20
+}
+
+// Roughly what a fetched row costs to keep: the values plus the slice and
+// string headers pointing at them.
+func rowSize(row []string) int64 {
+	size := int64(24 + 16 * len(row))
+	for _, value := range row {
+		size += int64(len(value))
+	}
+
+	return size
+}
+
+func memoryExceeded(rows int) {
+	showToast(trf("Stopped after %s rows, at the max_memory limit of %d " +
+		      "MB. Add a LIMIT, or an -- out: comment to write them " +
+		      "to a file instead", groupThousands(rows),
+		      config.MaxMemory), true)
+}
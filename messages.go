@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"errors"
+	"strings"
+	"github.com/go-sql-driver/mysql"
+)
+
+const maxMessages int = 200
+
+type message struct {
+	at      time.Time
+	text    string
+	isError bool
+	repeats int
+
+	// For failed statements, shown by the error command.
+	query string
+	err   error
+}
+
+// Everything shown with showMessage or showError, oldest first, so it can
+// be read back after the status bar has moved on.
+var messages []message
+
+var lastError *message
+
+func init() {
+	registerCommand(command {
+		name: "messages",
+		help: "Show the recent messages and errors",
+		run:  messagesCommand,
+	})
+
+	registerCommand(command {
+		name: "error",
+		help: "Show the last error in full, with its code and statement",
+		run:  errorCommand,
+	})
+}
+
+func recordMessage(text string, isError bool) *message {
+	if isError {
+		logEvent(levelError, "error shown", "message", text)
+	} else {
+		logEvent(levelDebug, "message shown", "message", text)
+	}
+
+	// Live views retry every few seconds, which would otherwise fill the
+	// history with the same error.
+	if n := len(messages); n > 0 && messages[n - 1].text == text {
+		messages[n - 1].at = time.Now()
+		messages[n - 1].repeats++
+		return &messages[n - 1]
+	}
+
+	if len(messages) == maxMessages {
+		messages = append(messages[:0], messages[1:]...)
+	}
+
+	messages = append(messages, message {
+		at:      time.Now(),
+		text:    text,
+		isError: isError,
+	})
+
+	return &messages[len(messages) - 1]
+}
+
+func showMessage(text string) {
+	status.Text = text
+	recordMessage(text, false)
+}
+
+func showError(text string) {
+	showQueryError("", errors.New(text))
+}
+
+// Errors are often too long for the status bar, so the error command shows
+// all of it along with the statement that caused it.
+func showQueryError(query string, err error) {
+	status.Text = err.Error()
+	recordError(query, err)
+}
+
+func recordError(query string, err error) {
+	// Copied so it survives being dropped from the history.
+	m := *recordMessage(err.Error(), true)
+	m.query = query
+	m.err = err
+	lastError = &m
+
+	if connectionLost(err) {
+		displayToast("Lost the connection to the server", true)
+	}
+}
+
+func (m message) String() string {
+	line := m.at.Format("15:04:05") + "  " + m.text
+	if m.isError {
+		line = m.at.Format("15:04:05") + "! " + m.text
+	}
+
+	if m.repeats > 0 {
+		line += fmt.Sprintf(" (x%d)", m.repeats + 1)
+	}
+
+	return line
+}
+
+func messagesCommand(args []string) error {
+	if len(messages) == 0 {
+		status.Text = tr("No messages yet")
+		return nil
+	}
+
+	lines := []string {}
+	for _, m := range messages {
+		lines = append(lines, m.String())
+	}
+
+	showPopup(tr("Messages"), strings.Join(lines, "\n"), false)
+	viewer.SetCursor(len([]rune(viewer.GetText())))
+	return nil
+}
+
+func errorCommand(args []string) error {
+	if lastError == nil {
+		status.Text = tr("No errors yet")
+		return nil
+	}
+
+	showPopup(tr("Last error"), errorDetail(*lastError), false)
+	return nil
+}
+
+// Breaks text into lines of at most width columns, at spaces where it can.
+func wrapText(text string, width int) string {
+	lines := []string {}
+	line := ""
+
+	for _, word := range strings.Fields(text) {
+		if line != "" && displayWidth(line + " " + word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+
+	return strings.Join(append(lines, line), "\n")
+}
+
+func errorDetail(m message) string {
+	var text strings.Builder
+
+	fmt.Fprintf(&text, "At %s\n\n", m.at.Format("2006-01-02 15:04:05"))
+
+	if mysqlErr, ok := m.err.(*mysql.MySQLError); ok {
+		fmt.Fprintf(&text, "Error number: %d\n", mysqlErr.Number)
+
+		if mysqlErr.SQLState != [5]byte {} {
+			fmt.Fprintf(&text, "SQLSTATE:     %s\n",
+				    mysqlErr.SQLState[:])
+		}
+
+		fmt.Fprintf(&text, "\n%s\n", wrapText(mysqlErr.Message, 72))
+	} else {
+		fmt.Fprintf(&text, "%s\n", wrapText(m.text, 72))
+	}
+
+	if m.query != "" {
+		fmt.Fprintf(&text, "\nStatement:\n\n%s\n",
+			    strings.TrimSpace(m.query))
+	}
+
+	return text.String()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+const defaultNotifyAfter int = 10
+
+// Terminals with focus reporting enabled send these when their window gains
+// or loses focus.
+const focusIn string = "\x1b[I"
+const focusOut string = "\x1b[O"
+
+var seqFocusIn  int
+var seqFocusOut int
+
+// Assumed until the terminal says otherwise, since not all of them report
+// focus.
+var terminalFocused = true
+
+var lastKeyAt time.Time
+
+func enableFocusReporting() {
+	if seqFocusIn == 0 {
+		seqFocusIn = escapebox.Register(focusIn)
+		seqFocusOut = escapebox.Register(focusOut)
+	}
+
+	fmt.Fprint(os.Stdout, "\x1b[?1004h")
+}
+
+func disableFocusReporting() {
+	fmt.Fprint(os.Stdout, "\x1b[?1004l")
+}
+
+func handleFocusEvent(ev escapebox.Event) bool {
+	if ev.Type == termbox.EventKey {
+		lastKeyAt = time.Now()
+	}
+
+	switch {
+	case seqFocusIn != 0 && ev.Seq == seqFocusIn:
+		terminalFocused = true
+	case seqFocusOut != 0 && ev.Seq == seqFocusOut:
+		terminalFocused = false
+	default:
+		return false
+	}
+
+	return true
+}
+
+// tmux only passes escape sequences it doesn't know on to the terminal when
+// they're wrapped like this, with their escapes doubled.
+func tmuxPassthrough(sequence string) string {
+	if os.Getenv("TMUX") == "" {
+		return sequence
+	}
+
+	return "\x1bPtmux;" + strings.Replace(sequence, "\x1b", "\x1b\x1b", -1) +
+	       "\x1b\\"
+}
+
+// Rings the bell or sends a desktop notification (OSC 9) when something
+// that took a while finishes, unless the user has been typing since it
+// started and so is presumably watching.
+func notifyIfLong(what string, started time.Time) {
+	elapsed := time.Since(started)
+	threshold := time.Duration(config.NotifyAfter) * time.Second
+
+	if config.Notify == "off" || elapsed < threshold {
+		return
+	}
+
+	if terminalFocused && lastKeyAt.After(started) {
+		return
+	}
+
+	text := fmt.Sprintf("%s after %.0fs", what, elapsed.Seconds())
+
+	if config.Notify == "osc" {
+		fmt.Fprint(os.Stdout, tmuxPassthrough("\x1b]9;prequel: " + text +
+						      "\a"))
+		return
+	}
+
+	fmt.Fprint(os.Stdout, "\a")
+}
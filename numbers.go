@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// Most digits after the decimal point the decimals setting allows, as many
+// as a DECIMAL column can have.
+const maxDecimals int = 30
+
+// Types whose values have a fractional part for the decimals setting to
+// round. Years and bits aren't quantities, so get no separators either.
+var fractionalTypes = map[string]bool {
+	"decimal": true, "float": true, "double": true,
+}
+
+func checkDecimals(decimals *int) error {
+	if decimals != nil && (*decimals < 0 || *decimals > maxDecimals) {
+		return errors.New(tr("The decimals setting must be between 0 " +
+				     "and 30"))
+	}
+
+	return nil
+}
+
+// Returns which columns are numbers, and which of those have fractions.
+func numberColumns(kinds []string) ([]bool, []bool) {
+	numeric := make([]bool, len(kinds))
+	fractional := make([]bool, len(kinds))
+
+	for i, kind := range kinds {
+		name := strings.TrimPrefix(strings.ToLower(kind), "unsigned ")
+		numeric[i] = numericTypes[name] && name != "year" && name != "bit"
+		fractional[i] = fractionalTypes[name]
+	}
+
+	return numeric, fractional
+}
+
+// Rounds half away from zero, like MySQL's ROUND, working on the digits so
+// DECIMAL values too long for a float64 keep their precision.
+func roundDecimal(number string, places int) string {
+	sign, digits, fraction := splitDecimal(number)
+	if digits == "" {
+		return number
+	}
+
+	fraction = strings.TrimPrefix(fraction, ".")
+	if len(fraction) <= places {
+		fraction += strings.Repeat("0", places - len(fraction))
+	} else {
+		roundUp := fraction[places] >= '5'
+		fraction = fraction[:places]
+
+		if roundUp {
+			all := []byte(digits + fraction)
+			i := len(all) - 1
+			for ; i >= 0 && all[i] == '9'; i-- {
+				all[i] = '0'
+			}
+
+			if i >= 0 {
+				all[i]++
+			} else {
+				all = append([]byte {'1'}, all...)
+			}
+
+			digits = string(all[:len(all) - places])
+			fraction = string(all[len(all) - places:])
+		}
+	}
+
+	if places > 0 {
+		return sign + digits + "." + fraction
+	}
+
+	return sign + digits
+}
+
+// Formats a number for the grid according to the thousands and decimals
+// settings. Returns false if that leaves it as it was.
+func formatNumber(value string, fractional bool) (string, bool) {
+	formatted := value
+
+	if fractional && config.Decimals != nil {
+		formatted = roundDecimal(formatted, *config.Decimals)
+	}
+
+	if config.Thousands {
+		formatted = groupDigits(formatted)
+	}
+
+	return formatted, formatted != value
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+// Terminals with bracketed paste enabled wrap pasted text in these, so it
+// can be inserted in one go instead of being replayed as keystrokes (which
+// in command mode would run as editor commands).
+const pasteStart string = "\x1b[200~"
+const pasteEnd string = "\x1b[201~"
+
+var seqPasteStart int
+var seqPasteEnd   int
+
+var pasting bool
+var pasted  []rune
+
+func enableBracketedPaste() {
+	if seqPasteStart == 0 {
+		seqPasteStart = escapebox.Register(pasteStart)
+		seqPasteEnd = escapebox.Register(pasteEnd)
+	}
+
+	fmt.Fprint(os.Stdout, "\x1b[?2004h")
+}
+
+func disableBracketedPaste() {
+	fmt.Fprint(os.Stdout, "\x1b[?2004l")
+}
+
+// Inserts the pasted text into whatever would have received the keys: the
+// status bar prompt if it's open, otherwise the editor.
+func finishPaste() {
+	text := strings.Replace(string(pasted), "\r\n", "\n", -1)
+	text = strings.Replace(text, "\r", "\n", -1)
+	pasted = nil
+
+	if input.active {
+		line := strings.Join(strings.Fields(text), " ")
+		input.input = append(input.input, []rune(line)...)
+		status.Text = input.String()
+		return
+	}
+
+	if container.Focused != &editor {
+		return
+	}
+
+	if config.InsertSpaces {
+		text = expandTabs(text, config.TabWidth)
+	}
+
+	insertAtCursor(text)
+}
+
+func handlePasteEvent(ev escapebox.Event) bool {
+	if seqPasteStart != 0 && ev.Seq == seqPasteStart {
+		pasting = true
+		pasted = nil
+		return true
+	}
+
+	if !pasting {
+		return false
+	}
+
+	if ev.Seq == seqPasteEnd {
+		pasting = false
+		finishPaste()
+		return true
+	}
+
+	if ev.Type != termbox.EventKey {
+		return true
+	}
+
+	switch {
+	case ev.Ch != 0:
+		pasted = append(pasted, ev.Ch)
+	case ev.Key == termbox.KeyEnter, ev.Key == termbox.KeyCtrlJ:
+		pasted = append(pasted, '\n')
+	case ev.Key == termbox.KeyTab:
+		pasted = append(pasted, '\t')
+	case ev.Key == termbox.KeySpace:
+		pasted = append(pasted, ' ')
+	}
+
+	return true
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"github.com/briansteffens/tui"
+)
+
+// A copy of earlier results kept beside the live ones, so a reference query's
+// rows stay in view while another is reworked.
+var pinned tui.DetailView
+var pinnedVisible bool
+
+func init() {
+	registerCommand(command {
+		name:  "pin",
+		usage: "[off]",
+		help:  "Keep the results in a pane beside the next ones",
+		run:   pinCommand,
+	})
+}
+
+func pinResults() error {
+	if len(results.Columns) == 0 {
+		return errors.New(tr("There are no results to pin"))
+	}
+
+	pinned = tui.DetailView {
+		Columns:    results.Columns,
+		Rows:       results.Rows,
+		RowBg:      theme.RowBg,
+		RowBgAlt:   theme.RowBgAlt,
+		SelectedBg: theme.SelectedBg,
+	}
+	pinnedVisible = true
+
+	updateControls()
+	resizeHandler()
+	showMessage(tr("Pinned the results (run pin off to close them)"))
+	return nil
+}
+
+func unpinResults() {
+	if container.Focused == &pinned {
+		container.Focused = &results
+	}
+
+	pinnedVisible = false
+	pinned = tui.DetailView {}
+
+	updateControls()
+	resizeHandler()
+}
+
+// Splits the results' area between them and the pinned copy, which goes
+// first: on the left, or on top when the results are already beside the
+// editor. A blank column separates them side by side.
+func layoutPinned() {
+	pinned.Bounds = results.Bounds
+
+	if state.Layout == layoutEditorLeft {
+		pinned.Bounds.Height = results.Bounds.Height / 2
+		results.Bounds.Top += pinned.Bounds.Height
+		results.Bounds.Height -= pinned.Bounds.Height
+		return
+	}
+
+	pinned.Bounds.Width = (results.Bounds.Width - 1) / 2
+	results.Bounds.Left += pinned.Bounds.Width + 1
+	results.Bounds.Width -= pinned.Bounds.Width + 1
+}
+
+func pinCommand(args []string) error {
+	switch {
+	case len(args) == 0:
+		return pinResults()
+
+	case len(args) == 1 && args[0] == "off":
+		unpinResults()
+		return nil
+	}
+
+	return usageError("pin")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"database/sql"
+)
+
+func init() {
+	registerCommand(command {
+		name: "pool",
+		help: "Show the connection pool's state, refreshing",
+		run:  poolCommand,
+	})
+}
+
+// The editor holds on to a connection of its own, as does the demo, so
+// the pool needs at least one more for everything else, including the KILL
+// QUERY that stops the editor's statement.
+func minPoolSize() int {
+	if demoMode {
+		return 3
+	}
+
+	return 2
+}
+
+// Applies the pool_* settings. Unset ones keep database/sql's defaults.
+func configurePool(handle *sql.DB) {
+	if config.PoolSize > 0 {
+		size := config.PoolSize
+		if size < minPoolSize() {
+			size = minPoolSize()
+		}
+
+		handle.SetMaxOpenConns(size)
+	}
+
+	if config.PoolIdle > 0 {
+		handle.SetMaxIdleConns(config.PoolIdle)
+	}
+
+	if config.PoolLifetime > 0 {
+		handle.SetConnMaxLifetime(time.Duration(config.PoolLifetime) *
+					  time.Second)
+	}
+}
+
+func poolLimit(limit int) string {
+	if limit <= 0 {
+		return tr("unlimited")
+	}
+
+	return fmt.Sprint(limit)
+}
+
+func poolStatus() resultSet {
+	stats := db.Stats()
+
+	lifetime := tr("forever")
+	if config.PoolLifetime > 0 {
+		lifetime = fmt.Sprintf("%ds", config.PoolLifetime)
+	}
+
+	row := func(name string, value interface{}) []string {
+		return []string {tr(name), fmt.Sprint(value)}
+	}
+
+	return resultSet {
+		columns: []string {"setting", "value"},
+		rows:    [][]string {
+			row("Max open", poolLimit(stats.MaxOpenConnections)),
+			row("Open", stats.OpenConnections),
+			row("In use", stats.InUse),
+			row("Idle", stats.Idle),
+			row("Waited for a connection", stats.WaitCount),
+			row("Total wait time", stats.WaitDuration),
+			row("Closed over the idle limit", stats.MaxIdleClosed),
+			row("Closed after idling", stats.MaxIdleTimeClosed),
+			row("Closed at max lifetime", stats.MaxLifetimeClosed),
+			row("Max lifetime", lifetime),
+		},
+	}
+}
+
+func poolCommand(args []string) error {
+	return startLiveView("pool", func() (resultSet, error) {
+		return poolStatus(), nil
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"strings"
+	"encoding/base64"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+	"github.com/briansteffens/tui"
+)
+
+const popupMargin int = 4
+
+// A read-only text viewer drawn over the rest of the UI. Movement keys are
+// passed through to the underlying EditBox so it can be scrolled, anything
+// that would edit the text is swallowed.
+type popup struct {
+	tui.EditBox
+
+	title         string
+	previousFocus tui.Control
+
+	// Offsets of section headings, which n and N jump between.
+	sections []int
+}
+
+var viewer       popup
+var popupVisible bool
+
+var popupMovementKeys = map[termbox.Key]bool {
+	termbox.KeyArrowUp:    true,
+	termbox.KeyArrowDown:  true,
+	termbox.KeyArrowLeft:  true,
+	termbox.KeyArrowRight: true,
+	termbox.KeyHome:       true,
+	termbox.KeyEnd:        true,
+	termbox.KeyPgup:       true,
+	termbox.KeyPgdn:       true,
+}
+
+const popupMovementChars string = "hjklwb0gG"
+
+func showPopup(title, text string, highlight bool) {
+	viewer.title = title
+	viewer.EditBox = tui.EditBox {}
+	viewer.sections = nil
+
+	if highlight {
+		viewer.Highlighter = highlighter
+	}
+
+	viewer.SetText(text)
+
+	if !popupVisible {
+		viewer.previousFocus = container.Focused
+	}
+
+	popupVisible = true
+	updateControls()
+	resizeHandler()
+	container.Focused = &viewer
+
+	status.Text = title + tr("  (e: copy to editor, y: copy to " +
+				 "clipboard, |: open in pager, q: close)")
+}
+
+func showSectionedPopup(title, text string, sections []int) {
+	showPopup(title, text, false)
+	viewer.sections = sections
+
+	status.Text = title + tr("  (n/N: next/previous section, e: copy " +
+				 "to editor, y: copy to clipboard, q: close)")
+}
+
+// Moves the cursor to the next section heading after it, or the previous
+// one before it.
+func (p *popup) jumpToSection(forward bool) {
+	cursor := p.GetCursor()
+
+	if forward {
+		for _, offset := range p.sections {
+			if offset > cursor {
+				p.SetCursor(offset)
+				return
+			}
+		}
+	} else {
+		for i := len(p.sections) - 1; i >= 0; i-- {
+			if p.sections[i] < cursor {
+				p.SetCursor(p.sections[i])
+				return
+			}
+		}
+	}
+}
+
+func closePopup() {
+	popupVisible = false
+	updateControls()
+
+	container.Focused = viewer.previousFocus
+	if container.Focused == nil {
+		container.Focused = &editor
+	}
+
+	status.Text = ""
+}
+
+func (p *popup) HandleEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey {
+		return p.EditBox.HandleEvent(ev)
+	}
+
+	switch {
+	case ev.Key == termbox.KeyEsc, ev.Ch == 'q':
+		closePopup()
+
+	case ev.Ch == 'y':
+		copyToClipboard(p.GetText())
+
+	case ev.Ch == '|':
+		page(p.GetText())
+
+	case ev.Ch == 'n', ev.Ch == 'N':
+		p.jumpToSection(ev.Ch == 'n')
+
+	case ev.Ch == 'e':
+		text := p.GetText()
+		closePopup()
+		insertAtCursor(text)
+
+	case popupMovementKeys[ev.Key], ev.Ch != 0 &&
+	     strings.ContainsRune(popupMovementChars, ev.Ch):
+		return p.EditBox.HandleEvent(ev)
+	}
+
+	return true
+}
+
+// Uses the OSC 52 escape sequence, which most terminal emulators (and tmux
+// with set-clipboard on) support, so this works over SSH too.
+func copyToClipboard(text string) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+	fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\a", encoded)
+
+	showMessage(trf("Copied %d characters to the clipboard",
+			len([]rune(text))))
+}
+
+func resizePopup() {
+	viewer.Bounds.Left = popupMargin
+	viewer.Bounds.Top = popupMargin / 2
+	viewer.Bounds.Width = container.Width - popupMargin * 2
+	viewer.Bounds.Height = container.Height - popupMargin - 1
+}
@@ -1,24 +1,32 @@
 package main
 
 import (
-	"errors"
+	"os"
 	"fmt"
+	"flag"
+	"time"
+	"strings"
+	"net/url"
 	"io/ioutil"
-	"encoding/json"
 	"database/sql"
 	"github.com/nsf/termbox-go"
 	"github.com/briansteffens/escapebox"
 	"github.com/briansteffens/tui"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 const minColumnWidth int = 5
 const maxColumnWidth int = 25
 
-const cursorStatementColor termbox.Attribute = termbox.Attribute(237)
-
 const tempSqlFile string = "prequel.sql"
 
+const fetchProgressInterval = 200 * time.Millisecond
+
+// Escape on its own is a key, and the mouse drags the split. Set again after
+// anything suspends the TUI, since tui.Init resets it.
+const inputMode termbox.InputMode = termbox.InputEsc | termbox.InputMouse
+
 type Connection struct {
 	Driver   string `json:"driver"`
 	Host     string `json:"host"`
@@ -26,34 +34,112 @@ type Connection struct {
 	User     string `json:"user"`
 	Password string `json:"password"`
 	Database string `json:"database"`
-}
 
-type Statement struct {
-	start  int
-	length int
+	// Passed on to the driver: the character set(s) to try in order and
+	// the collation for the connection, whether DATE and DATETIME values
+	// are parsed (rather than passed through as text) and the time zone
+	// they're read in.
+	Charset   string `json:"charset"`
+	Collation string `json:"collation"`
+	ParseTime bool   `json:"parse_time"`
+	Loc       string `json:"loc"`
+
+	// Turns on the lint warnings meant for live data, like SELECT *.
+	Production bool `json:"production"`
 }
 
+var config     Config
+var theme      Theme
+var sqlDialect dialect
 var db         *sql.DB
 var editor     tui.EditBox
 var results    tui.DetailView
 var container  tui.Container
 var status     tui.Label
-var statements []Statement
 var statement  Statement
 
+// Names the command whose output is in the results, for views that add
+// their own keys. Empty for query results.
+var resultsView string
+
 func resizeHandler() {
-	editor.Bounds.Width = container.Width
-	editor.Bounds.Height = container.Height / 2
+	left := 0
 
-	results.Bounds.Top = editor.Bounds.Height
-	results.Bounds.Width = container.Width
-	results.Bounds.Height = container.Height - editor.Bounds.Height - 1
+	if sidebarVisible {
+		left = sidebarWidth
+		browser.Bounds.Width = sidebarWidth - 1
+		browser.Bounds.Height = container.Height - 1
+		browser.refresh()
+	}
+
+	layoutPanes(left, container.Width - left, container.Height - 1)
 
-	status.Bounds.Top = results.Bounds.Bottom() + 1
+	status.Bounds.Top = container.Height - 1
 	status.Bounds.Width = container.Width
+	resizeIdentity()
+
+	if popupVisible {
+		resizePopup()
+	}
+
+	if recentVisible {
+		resizeRecent()
+	}
+
+	if toastVisible {
+		resizeToast()
+	}
+}
+
+func updateControls() {
+	container.Controls = []tui.Control {&results, &editor, &status,
+					     &identity}
+
+	if pinnedVisible {
+		container.Controls = append([]tui.Control {&pinned},
+					    container.Controls...)
+	}
+
+	// A maximized pane hides the other, which also takes it out of the
+	// focus order.
+	if zoomed != nil {
+		container.Controls = []tui.Control {zoomed, &status, &identity}
+	}
+
+	if sidebarVisible {
+		container.Controls = append(container.Controls, &browser)
+	}
+
+	// Drawn last so it covers the other controls.
+	if popupVisible {
+		container.Controls = append(container.Controls, &viewer)
+	}
+
+	if recentVisible {
+		container.Controls = append(container.Controls, &recent)
+	}
+
+	if toastVisible {
+		container.Controls = append(container.Controls, &toast)
+	}
 }
 
 func connect(conn Connection) (*sql.DB, error) {
+	handle, err := sql.Open(conn.Driver, dataSourceName(conn))
+	if err != nil {
+		return nil, err
+	}
+
+	configurePool(handle)
+	return handle, nil
+}
+
+func dataSourceName(conn Connection) string {
+	// SQLite databases are files, named by the database field.
+	if dialectForDriver(conn.Driver) == dialectSQLite {
+		return conn.Database
+	}
+
 	dsn := conn.User
 
 	if conn.Password != "" {
@@ -66,128 +152,334 @@ func connect(conn Connection) (*sql.DB, error) {
 
 	dsn += fmt.Sprintf("tcp(%s:%d)", conn.Host, conn.Port)
 
-	if conn.Database != "" {
-		dsn += "/" + conn.Database
+	dsn += "/" + conn.Database
+
+	if params := dsnParams(conn); len(params) > 0 {
+		dsn += "?" + strings.Join(params, "&")
 	}
 
-	return sql.Open(conn.Driver, dsn)
+	return dsn
 }
 
-func cursorInWhichStatement(cur int, ss []Statement) (Statement, error) {
-	for _, s := range ss {
-		if cur > s.start + s.length - 1 {
-			continue
-		}
+// The driver splits charset on commas itself and only unescapes loc.
+func dsnParams(conn Connection) []string {
+	params := []string {}
+
+	if conn.Charset != "" {
+		params = append(params, "charset=" + conn.Charset)
+	}
+
+	if conn.Collation != "" {
+		params = append(params, "collation=" + conn.Collation)
+	}
 
-		return s, nil
+	if conn.ParseTime {
+		params = append(params, "parseTime=true")
 	}
 
-	// Default to last statement if there is one
-	if len(ss) > 0 {
-		return ss[len(ss) - 1], nil
+	if conn.Loc != "" {
+		params = append(params, "loc=" + url.QueryEscape(conn.Loc))
 	}
 
-	return Statement {}, errors.New("Cursor not in statement")
+	return params
 }
 
-func editorTextChanged(e *tui.EditBox) {
-	err := ioutil.WriteFile(tempSqlFile, []byte(e.GetText()), 0644)
-	if err != nil {
-		panic(err)
+// Expand tabs to spaces, aligning to the next multiple of width columns.
+func expandTabs(text string, width int) string {
+	var expanded strings.Builder
+	column := 0
+
+	for _, ch := range text {
+		switch ch {
+		case '\t':
+			spaces := width - column % width
+			expanded.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+		case '\n':
+			expanded.WriteRune(ch)
+			column = 0
+		default:
+			expanded.WriteRune(ch)
+			column++
+		}
 	}
 
-	lineHighlighter(e)
+	return expanded.String()
 }
 
-func lineHighlighter(e *tui.EditBox) {
-	var cur, next *tui.Char
+func insertAtCursor(s string) {
+	text := []rune(editor.GetText())
+	cursor := editor.GetCursor()
 
-	statements = []Statement {}
-	statementStart := 0
+	setEditorText(string(text[:cursor]) + s + string(text[cursor:]), cursor)
+	editor.SetCursor(cursor + len([]rune(s)))
+}
 
-	chars := e.AllChars()
+func editorTextChanged(e *tui.EditBox) {
+	text := e.GetText()
 
-	for i := 0; i <= len(chars); i++ {
-		cur = next
+	if config.InsertSpaces && strings.ContainsRune(text, '\t') {
+		before := []rune(text)[:e.GetCursor()]
+		cursor := len([]rune(expandTabs(string(before), config.TabWidth)))
 
-		if i < len(chars) {
-			next = chars[i]
-		} else {
-			next = nil
-		}
+		// SetText fires OnTextChanged again with the expanded text.
+		setEditorText(expandTabs(text, config.TabWidth), 0)
+		e.SetCursor(cursor)
+		return
+	}
 
-		// Skip first iteration because cur won't be set yet.
-		if cur == nil {
-			continue
-		}
+	autosave.schedule(text)
 
-		// Statements end at unquoted semi-colons and EOF
-		if next == nil ||
-		   cur.Quote == tui.QuoteNone && cur.Char == ';' {
-			newStatement := Statement {
-				start: statementStart,
-				length: i - statementStart,
-			}
+	if journal.path != "" {
+		journal.schedule(text)
+	}
 
-			statementStart = i
+	if err := autosave.lastError(); err != nil {
+		showError(trf("Autosave failed: %s", err))
+	}
 
-			// Statements should include a trailing newline if
-			// present.
-			if next != nil && next.Char == '\n' {
-				newStatement.length++
-				statementStart++
-			}
+	lineHighlighter(e)
+}
 
-			statements = append(statements, newStatement)
-		}
+func lineHighlighter(e *tui.EditBox) {
+	// Done once the pending highlight catches up.
+	if highlightStale {
+		return
 	}
 
-	statement, _ = cursorInWhichStatement(e.GetCursor(), statements)
+	chars := e.AllChars()
+	doc.update(chars)
+
+	statement, _ = cursorInWhichStatement(e.GetCursor(), doc.statements)
 
 	for i := 0; i < len(chars); i++ {
 		if i >= statement.start &&
 		   i < statement.start + statement.length {
-			chars[i].Bg = cursorStatementColor
+			chars[i].Bg = theme.Statement
 		} else {
-			chars[i].Bg = termbox.ColorBlack
+			chars[i].Bg = theme.Background
+		}
+	}
+
+	hints := statementHints(doc.text, doc.tokens, statement)
+	warnings := lintWarnings(doc.text, doc.tokens, statement)
+
+	for _, warning := range warnings {
+		if warning.offset < len(chars) {
+			chars[warning.offset].Bg = theme.Warning
 		}
 	}
+
+	for _, hint := range hints {
+		if hint.offset < len(chars) {
+			chars[hint.offset].Bg = theme.Error
+		}
+	}
+
+	showHints(hints, warnings)
 }
 
 func handleContainerEvent(c *tui.Container, ev escapebox.Event) bool {
+	defer recoverEvent()
+
+	if logging(levelDebug) && ev.Type != termbox.EventInterrupt {
+		// What's typed into a password prompt stays out of the log.
+		ch := string(ev.Ch)
+		if input.active && input.secret && ev.Ch != 0 {
+			ch = "*"
+		}
+
+		logEvent(levelDebug, "event", "type", ev.Type, "key", ev.Key,
+			 "ch", ch, "seq", ev.Seq)
+	}
+
+	if ev.Type == termbox.EventInterrupt {
+		runPending()
+		return true
+	}
+
+	if handlePasteEvent(ev) {
+		return true
+	}
+
+	if handleFocusEvent(ev) {
+		return true
+	}
+
+	// A prompt takes Ctrl+C to cancel it, so it goes first.
+	if handlePromptEvent(ev) {
+		return true
+	}
+
+	if handleQuitEvent(ev) {
+		return true
+	}
+
+	if handleKeyBindingEvent(ev) {
+		return true
+	}
+
+	if handleHistoryEvent(ev) {
+		return true
+	}
+
+	if handleRecentEvent(ev) {
+		return true
+	}
+
+	if ev.Type == termbox.EventKey && ev.Key == termbox.KeyCtrlP {
+		openCommandPalette()
+		return true
+	}
+
 	if ev.Type == termbox.EventKey && ev.Key == termbox.KeyF5 {
 		runQuery()
 		return true
 	}
 
+	if ev.Type == termbox.EventKey && ev.Key == termbox.KeyF4 {
+		toggleSidebar()
+		return true
+	}
+
+	if handleSplitEvent(ev) {
+		return true
+	}
+
+	if handleBookmarkEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleKillEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleVariablesEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleSysMenuEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleBinlogEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleResultsPagerEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleConnectionsEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleLibraryEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleCellViewerEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &results && handleColumnJumpEvent(ev) {
+		return true
+	}
+
+	if c.Focused == &editor {
+		doc.noteKey(ev, editor.GetCursor())
+	}
+
 	return false
 }
 
 func runQuery() {
+	refreshHighlight()
+
+	if statement.directive {
+		results.Reset()
+		status.Text = trf("Delimiter is now %s", statement.delimiter)
+		return
+	}
+
+	query := statementQuery(doc.text, statement)
+
+	if path := outputFile(statement); path != "" {
+		exportQuery(query, path)
+		return
+	}
+
+	executeQuery(query)
+}
+
+// Runs a statement on the editor's connection and shows its results, first
+// asking if it looks like a large full scan. The editor, history, library,
+// macros and remote queries all come through here.
+func executeQuery(query string) {
+	confirmScan(query, func() {
+		runStatement(query)
+	})
+}
+
+func runStatement(query string) {
+	stopLiveView()
 	results.Reset()
 	status.Text = ""
 
-	query := ""
-	for i := statement.start; i < statement.start + statement.length; i++ {
-		ch, err := editor.GetChar(i)
-		if err != nil {
-			panic(err)
-		}
-		query += string(ch.Char)
+	started := time.Now()
+
+	showQueryState(true)
+	defer showQueryState(false)
+	defer notifyIfLong("Query finished", started)
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	var res *sql.Rows
+	conn, err := editorConnection()
+	if err == nil {
+		res, err = queryStatement(ctx, conn, query)
 	}
 
-	res, err := db.Query(query)
+	// Refused statements are kept out of the history.
+	if _, refused := err.(refusedError); refused {
+		showError(err.Error())
+		return
+	}
+
+	recordHistory(query)
 	if err != nil {
-		status.Text = fmt.Sprintf("%s", err)
+		if connectionLost(err) {
+			resetEditorConnection()
+		}
+
+		showQueryError(query, err)
 		return
 	}
 	defer res.Close()
 
+	trackTransaction(query)
+
 	columnNames, err := res.Columns()
 	if err != nil {
-		panic(err)
+		showQueryError(query, err)
+		return
+	}
+
+	types, err := res.ColumnTypes()
+	if err != nil {
+		showQueryError(query, err)
+		return
 	}
 
+	kinds := columnKinds(types)
+	numeric, fractional := numberColumns(kinds)
+	spatial := geometryColumns(types)
+	geometries := map[cellPos][]byte {}
+
+	times := displayTimes()
+	originals := map[cellPos]string {}
+
 	values := make([]interface{}, len(columnNames))
 	valuePointers := make([]interface{}, len(columnNames))
 
@@ -196,35 +488,131 @@ func runQuery() {
 	}
 
 	rows := make([][]string, 0)
+	progress := time.Now()
+
+	budget := memoryBudget()
+	used := int64(0)
+	exceeded := false
 
 	for res.Next() {
 		if err := res.Scan(valuePointers...); err != nil {
-			panic(err)
+			showQueryError(query, err)
+			return
 		}
 
 		row := make([]string, len(columnNames))
 
 		for i := 0; i < len(columnNames); i++ {
 			val := "null"
-			if values[i] != nil {
+			if t, ok := values[i].(time.Time); ok {
+				val = formatTime(t, kinds[i])
+			} else if values[i] != nil {
 				val = fmt.Sprintf("%s", values[i])
 			}
+
+			raw, ok := values[i].([]byte)
+			if spatial[i] && ok {
+				if text, err := geometryText(raw); err == nil {
+					val = text
+					geometries[cellPos {len(rows), i}] = raw
+				}
+			}
+
+			if times != nil && values[i] != nil &&
+			   isTemporal(kinds[i]) {
+				if text, ok := times.convert(val, kinds[i]); ok {
+					originals[cellPos {len(rows), i}] = val
+					val = text
+				}
+			}
+
+			if numeric[i] && values[i] != nil {
+				text, ok := formatNumber(val, fractional[i])
+				if ok {
+					originals[cellPos {len(rows), i}] = val
+					val = text
+				}
+			}
+
 			row[i] = val
 		}
 
 		rows = append(rows, row)
+
+		used += rowSize(row)
+		if budget > 0 && used > budget {
+			exceeded = true
+			break
+		}
+
+		if len(rows) % 1000 == 0 &&
+		   time.Since(progress) > fetchProgressInterval {
+			progress = time.Now()
+			drawStatus(trf("Fetched %s rows...",
+				       groupThousands(len(rows))))
+		}
+	}
+
+	if exceeded {
+		// Otherwise closing the rows would read the rest of them.
+		// Where the server can't be asked to stop, they're read and
+		// thrown away.
+		stopEditorStatement()
+		res.Close()
+	}
+
+	// The connection can drop partway through the rows. Stopping them
+	// early is an error too, but the rows so far are still shown.
+	if err := res.Err(); err != nil && !exceeded {
+		showQueryError(query, err)
+		return
+	}
+
+	status.Text = ""
+	showResults(columnNames, rows)
+	geometryCells = geometries
+
+	// Copies and tools get the values as the server sent them.
+	for pos, value := range originals {
+		rawCells[pos] = value
 	}
+	rememberResult(query, len(rows))
+	noteExplain(query, columnNames, rows)
 
+	if exceeded {
+		memoryExceeded(len(rows))
+	}
+}
+
+// Redraws the status bar straight away, for progress shown while the main
+// loop is busy.
+func drawStatus(text string) {
+	status.Text = text
+	status.Draw()
+	termbox.Flush()
+}
+
+func showResults(columnNames []string, rows [][]string) {
+	stopLiveView()
+	results.Reset()
+	resultsView = ""
+	setResults(columnNames, rows)
+}
+
+// Replaces the results without resetting the selection.
+func setResults(columnNames []string, rows [][]string) {
 	columns := make([]tui.Column, len(columnNames))
+	rawColumns = append([]string {}, columnNames...)
+	rows = escapeRows(rows)
 
 	for i := 0; i < len(columnNames); i++ {
-		columns[i].Name = columnNames[i]
+		columns[i].Name = escapeValue(columnNames[i])
 
-		width := len(columns[i].Name)
+		width := displayWidth(columns[i].Name)
 
 		for _, row := range rows {
-			if len(row[i]) > width {
-				width = len(row[i])
+			if w := displayWidth(row[i]); w > width {
+				width = w
 			}
 		}
 
@@ -243,21 +631,88 @@ func runQuery() {
 
 	results.Columns = columns
 	results.Rows = rows
+
+	if results.SelectedRow >= len(rows) {
+		results.SelectedRow = len(rows) - 1
+	}
+
+	if results.SelectedRow < 0 {
+		results.SelectedRow = 0
+	}
 }
 
 func main() {
-	configBytes, err := ioutil.ReadFile("config.json")
+	flag.Parse()
+
+	// The demo doesn't need a config.json, but uses one if it's there.
+	configBytes, err := ioutil.ReadFile(configPath)
+	if err != nil && demoMode {
+		configBytes, err = []byte("{}"), nil
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	config, err = parseConfig(configBytes)
+	if err != nil {
+		fmt.Printf("Error: config.json, %s\n", err)
+		return
+	}
+
+	if err := openLog(config.LogLevel, config.LogFile); err != nil {
+		fmt.Printf("Error: config.json, %s\n", err)
+		return
+	}
+	defer closeLog()
+
+	if err := loadCatalog(config.Locale); err != nil {
+		fmt.Printf("Error: config.json, %s\n", err)
+		return
+	}
+
+	theme, err = loadTheme(config.Theme, config.Colors)
 	if err != nil {
-		panic(err)
+		fmt.Printf("Error: config.json, %s\n", err)
+		return
+	}
+
+	if config.ReducedColor {
+		theme = theme.reduced()
 	}
 
-	connection := Connection{}
-	err = json.Unmarshal(configBytes, &connection)
+	truecolor = useTruecolor(config.Truecolor)
+	theme = theme.forOutput(truecolor)
+
+	loadState()
+	restoreProfile()
+
+	connection, err := config.profile(profileName)
 	if err != nil {
-		fmt.Println("Error: config.json, invalid json")
-		panic(err)
+		fmt.Printf("Error: config.json, %s\n", err)
+		return
+	}
+
+	if demoMode {
+		connection = demoConnection
 	}
 
+	baseConnection = config.Connection
+	config.Connection = connection
+
+	// Macros' placeholders are found by lexing them in the connection's
+	// dialect.
+	sqlDialect = dialectForDriver(connection.Driver)
+	registerMacros(config.Macros)
+
+	if err := compileHooks(config.Hooks); err != nil {
+		fmt.Printf("Error: config.json, %s\n", err)
+		return
+	}
+
+	registerTools(config.Tools)
+
 	if connection.Driver == "" {
 		fmt.Println("Error: config.json is missing the 'driver' " +
 			    "field");
@@ -272,53 +727,147 @@ func main() {
 
 	db, err = connect(connection)
 	if err != nil {
-		panic(err)
+		fmt.Printf("Error: %s\n", err)
+		return
 	}
 	defer db.Close()
+	defer cancelAll()
 
-	err = db.Ping()
+	ctx, cancel := queryContext()
+	err = db.PingContext(ctx)
+	cancel()
 	if err != nil {
-		panic(err)
+		fmt.Printf("Error: can't connect to %s: %s\n", connection, err)
+		logEvent(levelError, "connect failed", "to", connection,
+			 "error", err)
+		return
+	}
+
+	logEvent(levelInfo, "connected", "to", connection)
+
+	if demoMode {
+		if err := setUpDemo(); err != nil {
+			fmt.Printf("Error: setting up the demo failed: %s\n", err)
+			return
+		}
+		defer demoConn.Close()
 	}
 
+	if batchMode() {
+		if err := runBatch(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			db.Close()
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	autosave = newAutosaver(autosavePath(connection, mainBuffer))
+	defer autosave.flush()
+
+	// Fall back to the old working directory autosave file.
 	tempSql := "show tables;"
-	tempSqlBytes, err := ioutil.ReadFile(tempSqlFile)
+	tempSqlText, err := autosave.load()
 	if err == nil {
-		tempSql = string(tempSqlBytes);
+		tempSql = tempSqlText
+	} else if tempSqlBytes, err := ioutil.ReadFile(tempSqlFile); err == nil {
+		tempSql = string(tempSqlBytes)
+	}
+
+	if demoMode {
+		tempSql = demoScript
+	}
+
+	if config.InsertSpaces {
+		tempSql = expandTabs(tempSql, config.TabWidth)
 	}
 
 	tui.Init()
 	defer tui.Close()
+	enableTruecolor()
+
+	uiStarted = true
+	defer recoverCrash()
+
+	enableBracketedPaste()
+	defer disableBracketedPaste()
+
+	enableFocusReporting()
+	defer disableFocusReporting()
+
+	termbox.SetInputMode(inputMode)
 
 	editor = tui.EditBox {
-		Highlighter:   tui.BasicHighlighter,
-		Dialect:       tui.DialectMySQL,
+		Highlighter:   highlighter,
 		OnTextChanged: editorTextChanged,
 		OnCursorMoved: lineHighlighter,
+		TabWidth:      config.TabWidth,
 	}
-	editor.SetText(tempSql)
+	setEditorText(tempSql, 0)
+
+	startJournal(connection)
 
 	results = tui.DetailView {
 		Columns: []tui.Column {},
 		Rows: [][]string {},
-		RowBg: termbox.Attribute(0),
-		RowBgAlt: termbox.Attribute(236),
-		SelectedBg: termbox.Attribute(22),
+		RowBg: theme.RowBg,
+		RowBgAlt: theme.RowBgAlt,
+		SelectedBg: theme.SelectedBg,
 	}
 
 	status = tui.Label {
+		Fg: theme.StatusText,
+		Bg: theme.StatusBg,
+	}
+
+	saveTerminalTitle()
+	defer restoreTerminalTitle()
+	showIdentity(connection)
+
+	browser = schemaBrowser {
+		DetailView: tui.DetailView {
+			RowBg: theme.RowBg,
+			RowBgAlt: theme.RowBg,
+			SelectedBg: theme.SelectedBg,
+		},
 	}
 
 	container = tui.Container {
-		Controls: []tui.Control {&results, &editor, &status},
 		ResizeHandler: resizeHandler,
-		KeyBindingExit: tui.KeyBinding { Key: termbox.KeyCtrlC },
+		KeyBindingExit: tui.KeyBinding { Key: termbox.KeyCtrlBackslash },
 		KeyBindingFocusNext: tui.KeyBinding { Key: termbox.KeyTab },
 		KeyBindingFocusPrevious: tui.KeyBinding {
 			Seq: tui.SeqShiftTab,
 		},
 		HandleEvent: handleContainerEvent,
 	}
+	updateControls()
+
+	restoreSession()
+	defer saveSession()
+
+	watchConfig()
+
+	if listenAddress != "" {
+		stop, err := startRemoteServer(listenAddress)
+		if err != nil {
+			showError(trf("Remote control: %s", err))
+		} else {
+			defer stop()
+		}
+	}
+
+	if config.Overview {
+		if err := overviewCommand(nil); err != nil {
+			showError(err.Error())
+		}
+	}
+
+	offerJournals(orphanedJournals(connection))
 
 	tui.MainLoop(&container)
+
+	// Not deferred: after a crash the journal has to outlive main.
+	journal.discard()
 }
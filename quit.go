@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"io/ioutil"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+// tui only leaves MainLoop on its exit key (Ctrl+\, which quits without
+// asking), so quitting after the checks below unwinds out of it with this
+// panic, which main recovers from.
+type quitRequest struct{}
+
+// Set once the editor has been saved to a file from the quit prompt, since
+// the autosave is still failing.
+var savedElsewhere bool
+
+// Quitting is a clean exit, so the journal is discarded first. The panic
+// skips the end of main, where it otherwise would be.
+func quit() {
+	journal.discard()
+	panic(quitRequest {})
+}
+
+// The reasons not to quit yet.
+func quitConcerns() []string {
+	concerns := []string {}
+
+	if inTransaction {
+		concerns = append(concerns, tr("a transaction is open"))
+	}
+
+	if jobRunning() {
+		concerns = append(concerns, tr("a background job is running"))
+	}
+
+	if editorUnsaved() {
+		concerns = append(concerns, tr("the editor couldn't be saved"))
+	}
+
+	return concerns
+}
+
+func editorUnsaved() bool {
+	return !savedElsewhere && !autosave.saved()
+}
+
+func (a *autosaver) saved() bool {
+	a.flush()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return !a.dirty
+}
+
+func requestQuit() {
+	concerns := quitConcerns()
+	if len(concerns) == 0 {
+		quit()
+	}
+
+	options := []string {}
+	if inTransaction {
+		options = append(options, tr("c: commit"), tr("r: roll back"))
+	}
+	if editorUnsaved() {
+		options = append(options, tr("s: save to a file"))
+	}
+	options = append(options, tr("q: quit anyway"), tr("Enter: stay"))
+
+	question := trf("Quit? %s (%s) ", strings.Join(concerns, ", "),
+			strings.Join(options, ", "))
+
+	askFor(question, func(answer string) {
+		switch strings.TrimSpace(answer) {
+		case "c":
+			quitAfter("COMMIT")
+		case "r":
+			quitAfter("ROLLBACK")
+		case "s":
+			askForSavePath()
+		case "q":
+			quit()
+		}
+	})
+}
+
+func quitAfter(statement string) {
+	if !inTransaction {
+		requestQuit()
+		return
+	}
+
+	if err := endTransaction(statement); err != nil {
+		showError(trf("%s failed: %s", statement, err))
+		return
+	}
+
+	requestQuit()
+}
+
+func askForSavePath() {
+	askFor(tr("Save the editor to: "), func(path string) {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return
+		}
+
+		err := ioutil.WriteFile(path, []byte(editor.GetText()), 0600)
+		if err != nil {
+			showError(trf("Saving failed: %s", err))
+			return
+		}
+
+		savedElsewhere = true
+		requestQuit()
+	})
+}
+
+func handleQuitEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey || ev.Key != termbox.KeyCtrlC {
+		return false
+	}
+
+	requestQuit()
+	return true
+}
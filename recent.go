@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+	"github.com/briansteffens/tui"
+)
+
+// One key per statement. j and k are left out since they move the
+// selection.
+const recentKeys string = "1234567890abcdefghil"
+
+const recentMaxWidth int = 100
+
+// A short list of the statements last run on this connection, dropped down
+// over the editor, for re-running things in a tight loop without going
+// through the full history.
+type recentMenu struct {
+	tui.DetailView
+
+	queries       []string
+	previousFocus tui.Control
+}
+
+var recent        recentMenu
+var recentVisible bool
+
+func init() {
+	registerCommand(command {
+		name: "recent",
+		help: "Pick one of the last statements run to insert it",
+		run:  recentCommand,
+	})
+}
+
+func showRecent() {
+	queries := []string {}
+	for _, entry := range loadHistory(false) {
+		if len(queries) == len(recentKeys) {
+			break
+		}
+
+		queries = append(queries, entry.Query)
+	}
+
+	if len(queries) == 0 {
+		showMessage(tr("Nothing has been run on this connection yet"))
+		return
+	}
+
+	rows := [][]string {}
+	for i, query := range queries {
+		line := strings.Join(strings.Fields(query), " ")
+		rows = append(rows, []string {string(recentKeys[i]), line})
+	}
+
+	recent = recentMenu {
+		DetailView: tui.DetailView {
+			Rows:       rows,
+			RowBg:      theme.RowBg,
+			RowBgAlt:   theme.RowBgAlt,
+			SelectedBg: theme.SelectedBg,
+		},
+		queries: queries,
+	}
+
+	if !recentVisible {
+		recent.previousFocus = container.Focused
+	}
+
+	recentVisible = true
+	updateControls()
+	resizeRecent()
+	container.Focused = &recent
+
+	status.Text = tr("Press a statement's key or Enter to insert it, " +
+			 "Escape to close")
+}
+
+// Covers the top of the editor, or as much of it as the list needs.
+func resizeRecent() {
+	bounds := editor.Bounds
+
+	if bounds.Width > recentMaxWidth {
+		bounds.Width = recentMaxWidth
+	}
+
+	// One line for the column headings.
+	if bounds.Height > len(recent.Rows) + 1 {
+		bounds.Height = len(recent.Rows) + 1
+	}
+
+	recent.Bounds = bounds
+	recent.Columns = []tui.Column {
+		{Name: "key", Width: 4},
+		{Name: "statement", Width: bounds.Width - 4},
+	}
+}
+
+func closeRecent() {
+	recentVisible = false
+	updateControls()
+
+	container.Focused = recent.previousFocus
+	if container.Focused == nil {
+		container.Focused = &editor
+	}
+
+	status.Text = ""
+}
+
+func (m *recentMenu) pick(i int) {
+	if i < 0 || i >= len(m.queries) {
+		return
+	}
+
+	closeRecent()
+	container.Focused = &editor
+	insertQuery(m.queries[i])
+}
+
+func (m *recentMenu) HandleEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey {
+		return m.DetailView.HandleEvent(ev)
+	}
+
+	switch {
+	case ev.Key == termbox.KeyEsc, ev.Ch == 'q':
+		closeRecent()
+	case ev.Key == termbox.KeyEnter:
+		m.pick(m.SelectedRow)
+	case ev.Ch != 0 && strings.ContainsRune(recentKeys, ev.Ch):
+		m.pick(strings.IndexRune(recentKeys, ev.Ch))
+	default:
+		return m.DetailView.HandleEvent(ev)
+	}
+
+	return true
+}
+
+func recentCommand(args []string) error {
+	showRecent()
+	return nil
+}
+
+// Ctrl+E opens the menu from anywhere.
+func handleRecentEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey || ev.Key != termbox.KeyCtrlE {
+		return false
+	}
+
+	if recentVisible {
+		closeRecent()
+	} else {
+		showRecent()
+	}
+
+	return true
+}
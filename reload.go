@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"time"
+	"io/ioutil"
+	"sync/atomic"
+)
+
+const configPollInterval time.Duration = 2 * time.Second
+
+// config.json's modification time (in nanoseconds) as of the last load or
+// save, so only changes made elsewhere trigger a reload.
+var configStamp int64
+
+func configModTime() int64 {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return 0
+	}
+
+	return info.ModTime().UnixNano()
+}
+
+// Called after prequel writes config.json itself.
+func noteConfigWritten() {
+	atomic.StoreInt64(&configStamp, configModTime())
+}
+
+// Polls rather than using inotify and friends, which would mean another
+// dependency for something checked every couple of seconds.
+func watchConfig() {
+	noteConfigWritten()
+
+	go func() {
+		defer recoverCrash()
+
+		for range time.Tick(configPollInterval) {
+			stamp := configModTime()
+			if stamp == 0 {
+				continue
+			}
+
+			previous := atomic.SwapInt64(&configStamp, stamp)
+			if stamp != previous {
+				post(reloadConfig)
+			}
+		}
+	}()
+}
+
+// Applies a changed config.json. Anything invalid leaves the old config in
+// place. The connection in use is kept even if the top-level one changed;
+// switch with the connections view to use it.
+func reloadConfig() {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		showError(trf("Reloading config.json failed: %s", err))
+		return
+	}
+
+	next, err := parseConfig(data)
+	if err == nil {
+		err = compileHooks(next.Hooks)
+	}
+
+	if err != nil {
+		showError(trf("Not reloading config.json: %s", err))
+		return
+	}
+
+	var nextCatalog map[string]string
+	var logFile *os.File
+	var level logLevel
+
+	nextTheme, err := loadTheme(next.Theme, next.Colors)
+	if err == nil {
+		nextCatalog, err = readCatalog(next.Locale)
+	}
+
+	// The new log is opened before the old one is closed, so a log_file
+	// that can't be opened leaves the old one going.
+	if err == nil {
+		level, logFile, err = prepareLog(next.LogLevel, next.LogFile)
+	}
+
+	if err != nil {
+		compileHooks(config.Hooks)
+		showError(trf("Not reloading config.json: %s", err))
+		return
+	}
+
+	unregisterUserCommands()
+
+	catalog = nextCatalog
+	setLog(level, logFile)
+
+	baseConnection = next.Connection
+	next.Connection = config.Connection
+	config = next
+
+	registerMacros(config.Macros)
+	registerTools(config.Tools)
+	configurePool(db)
+
+	if config.ReducedColor {
+		nextTheme = nextTheme.reduced()
+	}
+	applyTheme(nextTheme.forOutput(truecolor))
+
+	editor.TabWidth = config.TabWidth
+
+	if resultsView == "connections" {
+		showConnections()
+	}
+
+	showMessage(tr("Reloaded config.json"))
+}
+
+// Recolors everything already on screen.
+func applyTheme(t Theme) {
+	theme = t
+
+	results.RowBg = theme.RowBg
+	results.RowBgAlt = theme.RowBgAlt
+	results.SelectedBg = theme.SelectedBg
+
+	pinned.RowBg = theme.RowBg
+	pinned.RowBgAlt = theme.RowBgAlt
+	pinned.SelectedBg = theme.SelectedBg
+
+	browser.RowBg = theme.RowBg
+	browser.RowBgAlt = theme.RowBg
+	browser.SelectedBg = theme.SelectedBg
+
+	status.Fg = theme.StatusText
+	status.Bg = theme.StatusBg
+	identity.Fg = theme.Comment
+	identity.Bg = theme.StatusBg
+
+	highlight(&editor)
+	lineHighlighter(&editor)
+}
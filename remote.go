@@ -0,0 +1,199 @@
+package main
+
+import (
+	"os"
+	"net"
+	"flag"
+	"errors"
+	"strings"
+	"unicode"
+	"net/http"
+	"io/ioutil"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"path/filepath"
+)
+
+const maxRemoteBody int64 = 16 << 20
+
+// Requests must send the session's token in this header, and the SQL as
+// this content type, which a browser can't send to another site without
+// asking it first.
+const remoteTokenHeader string = "X-Prequel-Token"
+const remoteContentType string = "application/sql"
+
+var listenAddress string
+
+// Generated each time prequel starts and written to remoteTokenPath.
+var remoteToken string
+
+func init() {
+	flag.StringVar(&listenAddress, "listen", "",
+		       "Accept queries from editors on this Unix socket path " +
+		       "(or host:port)")
+}
+
+// Runs the posted SQL as if it had been run from the editor, showing the
+// results in the TUI. With ?editor=1 the SQL is also put into the editor.
+func handleRemoteQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST the SQL to run", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only a web page would send an Origin, and none should be running
+	// queries.
+	if r.Header.Get("Origin") != "" {
+		http.Error(w, "Requests from browsers aren't accepted",
+			   http.StatusForbidden)
+		return
+	}
+
+	token := []byte(r.Header.Get(remoteTokenHeader))
+	if subtle.ConstantTimeCompare(token, []byte(remoteToken)) != 1 {
+		http.Error(w, "Missing or wrong " + remoteTokenHeader,
+			   http.StatusUnauthorized)
+		return
+	}
+
+	kind := strings.TrimSpace(strings.SplitN(r.Header.Get("Content-Type"),
+						 ";", 2)[0])
+	if kind != remoteContentType {
+		http.Error(w, "Send the SQL as " + remoteContentType,
+			   http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body,
+							maxRemoteBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := string(body)
+	toEditor := r.URL.Query().Get("editor") == "1"
+
+	post(func() {
+		if toEditor {
+			insertAtCursor(query)
+		}
+
+		executeQuery(query)
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Each address has a token file of its own, so several instances can listen
+// at once: /tmp/prequel.sock's is remote-tmp_prequel.sock.token.
+func remoteTokenPath(address string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || unicode.IsLetter(r) ||
+		   unicode.IsDigit(r) {
+			return r
+		}
+
+		return '_'
+	}, address)
+
+	return filepath.Join(configDir(),
+			     "remote-" + strings.Trim(name, "_") + ".token")
+}
+
+func newRemoteToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(data), nil
+}
+
+// TCP is only allowed on the loopback interface, since anything listening
+// further out would run SQL for the whole network.
+func checkLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return errors.New(tr("Only loopback addresses like " +
+				     "127.0.0.1:7777 can be listened on"))
+	}
+
+	return nil
+}
+
+// Clears out a socket left behind by an earlier run, but nothing else that
+// happens to have the name, and not one another instance is listening on.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode() & os.ModeSocket == 0 {
+		return nil
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return errors.New(trf("Something is already listening on %s",
+				      path))
+	}
+
+	return os.Remove(path)
+}
+
+// Listens on a Unix socket (readable only by this user) unless the address
+// looks like host:port. Returns a function that stops listening.
+func startRemoteServer(address string) (func(), error) {
+	network := "unix"
+	if strings.Contains(address, ":") {
+		network = "tcp"
+		if err := checkLoopback(address); err != nil {
+			return nil, err
+		}
+	} else if err := removeStaleSocket(address); err != nil {
+		return nil, err
+	}
+
+	// Listening first means a second instance on the same address fails
+	// before it can replace the first one's token.
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if network == "unix" {
+		if err := os.Chmod(address, 0600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	tokenPath := remoteTokenPath(address)
+
+	token, err := newRemoteToken()
+	if err == nil {
+		err = writeFileAtomic(tokenPath, []byte(token))
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	remoteToken = token
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", handleRemoteQuery)
+
+	go http.Serve(listener, mux)
+
+	return func() {
+		listener.Close()
+		os.Remove(tokenPath)
+	}, nil
+}
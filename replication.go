@@ -0,0 +1,147 @@
+package main
+
+import (
+	"strings"
+	"strconv"
+)
+
+// Shown first, in this order. Servers before MySQL 8.0.22 (and MariaDB) use
+// the older Master/Slave names, which are listed as alternatives.
+var replicationFields = [][]string {
+	{"Channel_Name", "Connection_name"},
+	{"Source_Host", "Master_Host"},
+	{"Source_Port", "Master_Port"},
+	{"Replica_IO_Running", "Slave_IO_Running"},
+	{"Replica_SQL_Running", "Slave_SQL_Running"},
+	{"Seconds_Behind_Source", "Seconds_Behind_Master"},
+	{"Replica_IO_State", "Slave_IO_State"},
+	{"Replica_SQL_Running_State", "Slave_SQL_Running_State"},
+	{"Last_IO_Error"},
+	{"Last_SQL_Error"},
+	{"Last_IO_Error_Timestamp"},
+	{"Last_SQL_Error_Timestamp"},
+	{"Source_Log_File", "Master_Log_File"},
+	{"Read_Source_Log_Pos", "Read_Master_Log_Pos"},
+	{"Relay_Source_Log_File", "Relay_Master_Log_File"},
+	{"Exec_Source_Log_Pos", "Exec_Master_Log_Pos"},
+	{"Retrieved_Gtid_Set"},
+	{"Executed_Gtid_Set"},
+}
+
+func init() {
+	registerCommand(command {
+		name: "replication",
+		help: "Show replication status, refreshing every few seconds",
+		run:  replicationCommand,
+	})
+}
+
+func replicaStatus() (resultSet, error) {
+	set, err := fetchResultSet("SHOW REPLICA STATUS")
+	if err == nil {
+		return set, nil
+	}
+
+	return fetchResultSet("SHOW SLAVE STATUS")
+}
+
+// Sums up a replication channel's health in a few words.
+func replicationHealth(row map[string]string) string {
+	io := row["Replica_IO_Running"] + row["Slave_IO_Running"]
+	sql := row["Replica_SQL_Running"] + row["Slave_SQL_Running"]
+	lag := row["Seconds_Behind_Source"] + row["Seconds_Behind_Master"]
+
+	problems := []string {}
+
+	if io != "Yes" {
+		problems = append(problems, "IO thread " + strings.ToLower(io))
+	}
+
+	if sql != "Yes" {
+		problems = append(problems, "SQL thread " + strings.ToLower(sql))
+	}
+
+	if seconds, err := strconv.Atoi(lag); err == nil && seconds > 0 {
+		problems = append(problems, "lagging " + formatUptime(lag))
+	}
+
+	if len(problems) == 0 {
+		return "OK"
+	}
+
+	return strings.Join(problems, ", ")
+}
+
+// Turns the status rows sideways, one line per field and one column per
+// replication channel, with the interesting fields at the top.
+func transposeReplicaStatus(set resultSet) resultSet {
+	columns := []string {"field"}
+	channels := []map[string]string {}
+
+	for i, row := range set.rows {
+		fields := map[string]string {}
+		for j, column := range set.columns {
+			fields[column] = row[j]
+		}
+
+		name := fields["Channel_Name"] + fields["Connection_name"]
+		if name == "" {
+			name = "channel " + strconv.Itoa(i + 1)
+		}
+
+		columns = append(columns, name)
+		channels = append(channels, fields)
+	}
+
+	line := func(field string) []string {
+		row := []string {field}
+		for _, channel := range channels {
+			row = append(row, channel[field])
+		}
+		return row
+	}
+
+	health := []string {"Health"}
+	for _, channel := range channels {
+		health = append(health, replicationHealth(channel))
+	}
+
+	rows := [][]string {health}
+	shown := map[string]bool {}
+
+	for _, names := range replicationFields {
+		for _, name := range names {
+			if columnIndex(set.columns, name) >= 0 {
+				rows = append(rows, line(name))
+				shown[name] = true
+				break
+			}
+		}
+	}
+
+	for _, column := range set.columns {
+		if !shown[column] {
+			rows = append(rows, line(column))
+		}
+	}
+
+	return resultSet { columns, rows }
+}
+
+func replicationCommand(args []string) error {
+	return startLiveView("replication", func() (resultSet, error) {
+		set, err := replicaStatus()
+		if err != nil {
+			return resultSet {}, err
+		}
+
+		if len(set.rows) == 0 {
+			return resultSet {
+				columns: []string {"field"},
+				rows:    [][]string {{"Not a replica"}},
+			}, nil
+		}
+
+		return transposeReplicaStatus(set), nil
+	})
+}
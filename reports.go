@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(command {
+		name:  "sizes",
+		usage: "[database]",
+		help:  "Report table sizes, largest first",
+		run:   sizesCommand,
+	})
+
+	registerCommand(command {
+		name: "overview",
+		help: "Summarize the server and its databases",
+		run:  overviewCommand,
+	})
+
+	registerCommand(command {
+		name: "dashboard",
+		help: "Show key server status counters and their rates",
+		run:  dashboardCommand,
+	})
+}
+
+func formatBytes(s string) string {
+	bytes, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+
+	units := []string {"B", "KB", "MB", "GB", "TB"}
+	unit := 0
+
+	for bytes >= 1024 && unit < len(units) - 1 {
+		bytes /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%.0f %s", bytes, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f %s", bytes, units[unit])
+}
+
+func sizesCommand(args []string) error {
+	database := config.Database
+
+	switch len(args) {
+	case 0:
+	case 1:
+		database = args[0]
+	default:
+		return usageError("sizes")
+	}
+
+	rows, err := queryStrings("SELECT table_name, engine, table_rows, " +
+		"data_length, index_length, data_length + index_length " +
+		"FROM information_schema.tables " +
+		"WHERE table_schema = ? AND table_type = 'BASE TABLE' " +
+		"ORDER BY data_length + index_length DESC, table_name",
+		database)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		for i := 3; i < len(row); i++ {
+			row[i] = formatBytes(row[i])
+		}
+	}
+
+	showResults([]string {"table", "engine", "rows (est.)", "data",
+			      "indexes", "total"}, rows)
+	status.Text = trf("%d tables in %s", len(rows), database)
+	return nil
+}
+
+func formatUptime(s string) string {
+	seconds, err := strconv.Atoi(s)
+	if err != nil {
+		return s
+	}
+
+	days := seconds / 86400
+	hours := seconds % 86400 / 3600
+	minutes := seconds % 3600 / 60
+
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh", days, hours)
+	}
+
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
+
+// Returns the values of the named global status counters.
+func globalStatus(names ...string) (map[string]string, error) {
+	args := []interface{} {}
+	for _, name := range names {
+		args = append(args, name)
+	}
+
+	rows, err := queryStrings("SHOW GLOBAL STATUS WHERE Variable_name IN (" +
+		strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ") +
+		")", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string {}
+	for _, row := range rows {
+		values[row[0]] = row[1]
+	}
+
+	return values, nil
+}
+
+func overviewCommand(args []string) error {
+	version, err := queryStrings("SELECT VERSION()")
+	if err != nil {
+		return err
+	}
+
+	counters, err := globalStatus("Uptime", "Threads_connected")
+	if err != nil {
+		return err
+	}
+
+	rows, err := queryStrings("SELECT s.schema_name, COUNT(t.table_name), " +
+		"IFNULL(SUM(t.data_length + t.index_length), 0) " +
+		"FROM information_schema.schemata s " +
+		"LEFT JOIN information_schema.tables t " +
+		"ON t.table_schema = s.schema_name " +
+		"GROUP BY s.schema_name ORDER BY s.schema_name")
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		row[2] = formatBytes(row[2])
+	}
+
+	showResults([]string {"database", "tables", "size"}, rows)
+	status.Text = trf("Server %s, up %s, %s connections, " +
+			  "%d databases", version[0][0],
+			  formatUptime(counters["Uptime"]),
+			  counters["Threads_connected"], len(rows))
+	return nil
+}
+
+type statusSample struct {
+	at       time.Time
+	counters map[string]float64
+}
+
+func sampleStatus(names ...string) (statusSample, error) {
+	values, err := globalStatus(names...)
+	if err != nil {
+		return statusSample {}, err
+	}
+
+	sample := statusSample {
+		at:       time.Now(),
+		counters: map[string]float64 {},
+	}
+
+	for name, value := range values {
+		sample.counters[name], _ = strconv.ParseFloat(value, 64)
+	}
+
+	return sample, nil
+}
+
+// Returns how much a counter grew per second between two samples, or false
+// if there is no earlier sample to compare against.
+func (s statusSample) rate(prev statusSample, name string) (float64, bool) {
+	seconds := s.at.Sub(prev.at).Seconds()
+	if prev.counters == nil || seconds <= 0 {
+		return 0, false
+	}
+
+	return (s.counters[name] - prev.counters[name]) / seconds, true
+}
+
+func hitRate(requests, misses float64) string {
+	if requests <= 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.2f%%", 100 * (1 - misses / requests))
+}
+
+func dashboardRows(prev, cur statusSample) [][]string {
+	rows := [][]string {
+		{"Threads connected",
+		 fmt.Sprintf("%.0f", cur.counters["Threads_connected"]), ""},
+		{"Threads running",
+		 fmt.Sprintf("%.0f", cur.counters["Threads_running"]), ""},
+	}
+
+	counters := []struct {
+		label string
+		name  string
+		bytes bool
+	} {
+		{"Queries", "Questions", false},
+		{"Slow queries", "Slow_queries", false},
+		{"Connections", "Connections", false},
+		{"Aborted connects", "Aborted_connects", false},
+		{"Bytes received", "Bytes_received", true},
+		{"Bytes sent", "Bytes_sent", true},
+	}
+
+	for _, c := range counters {
+		total := fmt.Sprintf("%.0f", cur.counters[c.name])
+		perSecond := "-"
+
+		rate, ok := cur.rate(prev, c.name)
+		if ok {
+			perSecond = fmt.Sprintf("%.1f", rate)
+		}
+
+		if c.bytes {
+			total = formatBytes(total)
+			if ok {
+				perSecond = formatBytes(fmt.Sprintf("%f", rate)) + "/s"
+			}
+		}
+
+		rows = append(rows, []string {c.label, total, perSecond})
+	}
+
+	requests := "Innodb_buffer_pool_read_requests"
+	misses := "Innodb_buffer_pool_reads"
+
+	overall := hitRate(cur.counters[requests], cur.counters[misses])
+	recent := "-"
+
+	if prev.counters != nil {
+		recent = hitRate(cur.counters[requests] - prev.counters[requests],
+				 cur.counters[misses] - prev.counters[misses])
+	}
+
+	return append(rows, []string {"Buffer pool hit rate", overall, recent})
+}
+
+func dashboardCommand(args []string) error {
+	names := []string {"Threads_connected", "Threads_running", "Questions",
+			   "Slow_queries", "Connections", "Aborted_connects",
+			   "Bytes_received", "Bytes_sent",
+			   "Innodb_buffer_pool_read_requests",
+			   "Innodb_buffer_pool_reads"}
+
+	// Only touched by one fetch at a time: the first one runs before the
+	// refresh goroutine starts.
+	var prev statusSample
+
+	return startLiveView("dashboard", func() (resultSet, error) {
+		cur, err := sampleStatus(names...)
+		if err != nil {
+			return resultSet {}, err
+		}
+
+		rows := dashboardRows(prev, cur)
+		prev = cur
+
+		return resultSet {
+			columns: []string {"metric", "total", "per second"},
+			rows:    rows,
+		}, nil
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"errors"
+	"strings"
+)
+
+const routineDelimiter string = "$$"
+
+func init() {
+	registerCommand(command {
+		name:  "routines",
+		usage: "[database]",
+		help:  "List stored procedures and functions",
+		run:   routinesCommand,
+	})
+
+	registerCommand(command {
+		name:  "routine",
+		usage: "<name>",
+		help:  "Show a stored routine's source, ready to re-create",
+		run:   routineCommand,
+	})
+
+	registerCommand(command {
+		name:  "triggers",
+		usage: "[table]",
+		help:  "Show the triggers on a table, or in the database",
+		run:   triggersCommand,
+	})
+
+	registerCommand(command {
+		name:  "events",
+		usage: "[database]",
+		help:  "List scheduled events",
+		run:   eventsCommand,
+	})
+
+	registerCommand(command {
+		name:  "event",
+		usage: "<name>",
+		help:  "Show a scheduled event's definition",
+		run:   eventCommand,
+	})
+}
+
+func routinesCommand(args []string) error {
+	database := config.Database
+
+	switch len(args) {
+	case 0:
+	case 1:
+		database = args[0]
+	default:
+		return usageError("routines")
+	}
+
+	rows, err := queryStrings("SELECT routine_name, routine_type, " +
+				  "dtd_identifier, created, last_altered, " +
+				  "routine_comment " +
+				  "FROM information_schema.routines " +
+				  "WHERE routine_schema = ? " +
+				  "ORDER BY routine_type, routine_name",
+				  database)
+	if err != nil {
+		return err
+	}
+
+	showResults([]string {"name", "type", "returns", "created",
+			      "modified", "comment"}, rows)
+	status.Text = trf("%d routines in %s", len(rows), database)
+	return nil
+}
+
+// Wraps a routine's CREATE statement so it can be run as-is from the editor
+// to replace the existing routine.
+func routineScript(routineType, name, definition string) string {
+	return fmt.Sprintf("DELIMITER %s\n" +
+			   "DROP %s IF EXISTS %s%s\n" +
+			   "%s%s\n" +
+			   "DELIMITER %s\n",
+			   routineDelimiter,
+			   routineType, name, routineDelimiter,
+			   definition, routineDelimiter,
+			   defaultDelimiter)
+}
+
+func routineCommand(args []string) error {
+	if len(args) != 1 {
+		return usageError("routine")
+	}
+
+	database, name := splitTableName(args[0])
+
+	rows, err := queryStrings("SELECT routine_type " +
+				  "FROM information_schema.routines " +
+				  "WHERE routine_schema = ? " +
+				  "AND routine_name = ?", database, name)
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return errors.New(trf("No routine named %s", name))
+	}
+
+	routineType := strings.ToUpper(rows[0][0])
+	qualified := qualifiedTable(database, name)
+
+	rows, err = queryStrings(fmt.Sprintf("SHOW CREATE %s %s", routineType,
+					     qualified))
+	if err != nil {
+		return err
+	}
+
+	// The definition is null without privileges on the routine.
+	if len(rows) == 0 || len(rows[0]) < 3 || rows[0][2] == "null" {
+		return errors.New(trf("Can't read the definition of %s", name))
+	}
+
+	script := routineScript(routineType, qualified, rows[0][2])
+	showPopup(routineType + " " + name, script, true)
+	return nil
+}
+
+func showTriggers(database, table string) error {
+	query := "SELECT trigger_name, action_timing, event_manipulation, " +
+		 "event_object_table, action_statement " +
+		 "FROM information_schema.triggers " +
+		 "WHERE trigger_schema = ?"
+	args := []interface{} {database}
+
+	if table != "" {
+		query += " AND event_object_table = ?"
+		args = append(args, table)
+	}
+
+	query += " ORDER BY event_object_table, action_timing, " +
+		 "event_manipulation, action_order"
+
+	rows, err := queryStrings(query, args...)
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		status.Text = tr("No triggers found")
+		return nil
+	}
+
+	script := fmt.Sprintf("DELIMITER %s\n", routineDelimiter)
+
+	for _, row := range rows {
+		script += fmt.Sprintf("\n-- %s %s on %s\n" +
+				      "CREATE TRIGGER %s %s %s ON %s " +
+				      "FOR EACH ROW\n%s%s\n",
+				      row[1], row[2], row[3],
+				      quoteIdentifier(row[0]), row[1], row[2],
+				      qualifiedTable(database, row[3]),
+				      row[4], routineDelimiter)
+	}
+
+	script += fmt.Sprintf("\nDELIMITER %s\n", defaultDelimiter)
+
+	title := fmt.Sprintf("%d triggers", len(rows))
+	if table != "" {
+		title += " on " + table
+	}
+
+	showPopup(title, script, true)
+	return nil
+}
+
+func triggersCommand(args []string) error {
+	switch len(args) {
+	case 0:
+		return showTriggers(config.Database, "")
+	case 1:
+		return showTriggers(splitTableName(args[0]))
+	}
+
+	return usageError("triggers")
+}
+
+func eventsCommand(args []string) error {
+	database := config.Database
+
+	switch len(args) {
+	case 0:
+	case 1:
+		database = args[0]
+	default:
+		return usageError("events")
+	}
+
+	rows, err := queryStrings("SELECT event_name, status, " +
+		"IF(execute_at IS NOT NULL, CONCAT('AT ', execute_at), " +
+		"CONCAT('EVERY ', interval_value, ' ', interval_field, " +
+		"IFNULL(CONCAT(' STARTS ', starts), ''), " +
+		"IFNULL(CONCAT(' ENDS ', ends), ''))), " +
+		"last_executed, event_definition " +
+		"FROM information_schema.events " +
+		"WHERE event_schema = ? ORDER BY event_name", database)
+	if err != nil {
+		return err
+	}
+
+	showResults([]string {"name", "status", "schedule", "last run",
+			      "body"}, rows)
+	status.Text = trf("%d events in %s", len(rows), database)
+	return nil
+}
+
+func eventCommand(args []string) error {
+	if len(args) != 1 {
+		return usageError("event")
+	}
+
+	database, name := splitTableName(args[0])
+	qualified := qualifiedTable(database, name)
+
+	rows, err := queryStrings("SHOW CREATE EVENT " + qualified)
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 || len(rows[0]) < 4 || rows[0][3] == "null" {
+		return errors.New(trf("Can't read the definition of %s", name))
+	}
+
+	script := routineScript("EVENT", qualified, rows[0][3])
+	showPopup("EVENT " + name, script, true)
+	return nil
+}
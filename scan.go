@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Statements MySQL can EXPLAIN.
+var explainable = map[string]bool {
+	"SELECT":  true,
+	"WITH":    true,
+	"TABLE":   true,
+	"UPDATE":  true,
+	"DELETE":  true,
+	"INSERT":  true,
+	"REPLACE": true,
+}
+
+// Returns the table EXPLAIN expects the statement to read the most rows of
+// in a full scan, and how many. Any trouble explaining it is left for the
+// statement itself to report when it runs.
+func largestScan(query string) (string, int) {
+	conn, err := editorConnection()
+	if err != nil {
+		return "", 0
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	res, err := conn.QueryContext(ctx, "EXPLAIN " + query)
+	if err != nil {
+		return "", 0
+	}
+	defer res.Close()
+
+	columns, rows, err := scanStrings(res)
+	if err != nil {
+		return "", 0
+	}
+
+	index := map[string]int {}
+	for i, column := range columns {
+		index[strings.ToLower(column)] = i
+	}
+
+	tableColumn, ok1 := index["table"]
+	typeColumn, ok2 := index["type"]
+	rowsColumn, ok3 := index["rows"]
+	if !ok1 || !ok2 || !ok3 {
+		return "", 0
+	}
+
+	table := ""
+	largest := 0
+
+	for _, row := range rows {
+		estimate, err := strconv.Atoi(row[rowsColumn])
+		if err != nil || row[typeColumn] != "ALL" || estimate <= largest {
+			continue
+		}
+
+		table = row[tableColumn]
+		largest = estimate
+	}
+
+	return table, largest
+}
+
+// Runs the statement, first asking if EXPLAIN thinks it will scan more rows
+// than the scan_warning setting allows.
+func confirmScan(query string, run func()) {
+	words := leadingWords(query)
+
+	if config.ScanWarning <= 0 || sqlDialect != dialectMySQL ||
+	   !explainable[words[0]] {
+		run()
+		return
+	}
+
+	drawStatus(tr("Checking the query plan..."))
+	table, rows := largestScan(query)
+	status.Text = ""
+
+	if rows <= config.ScanWarning {
+		run()
+		return
+	}
+
+	confirm(trf("This scans about %s rows of %s. Run it anyway?",
+		    groupThousands(rows), table), run)
+}
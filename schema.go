@@ -0,0 +1,478 @@
+package main
+
+import (
+	"fmt"
+	"database/sql"
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+	"github.com/briansteffens/tui"
+)
+
+const sidebarWidth int = 30
+
+const previewLimit int = 100
+
+func init() {
+	registerCommand(command {
+		name:  "ddl",
+		usage: "<table>",
+		help:  "Show a table's CREATE TABLE statement",
+		run:   ddlCommand,
+	})
+}
+
+type schemaNodeKind int
+
+const (
+	schemaDatabase schemaNodeKind = iota
+	schemaTable
+	schemaColumn
+)
+
+type schemaNode struct {
+	kind     schemaNodeKind
+	name     string
+	detail   string
+	parent   *schemaNode
+	children []*schemaNode
+	expanded bool
+	loaded   bool
+}
+
+// A tree of databases, tables and columns shown in a single column
+// DetailView. Children are loaded from information_schema the first time a
+// node is expanded.
+type schemaBrowser struct {
+	tui.DetailView
+
+	roots   []*schemaNode
+	visible []*schemaNode
+}
+
+var browser        schemaBrowser
+var sidebarVisible bool
+
+func (n *schemaNode) database() string {
+	for n.parent != nil {
+		n = n.parent
+	}
+
+	return n.name
+}
+
+func (n *schemaNode) table() string {
+	switch n.kind {
+	case schemaTable:
+		return n.name
+	case schemaColumn:
+		return n.parent.name
+	}
+
+	return ""
+}
+
+func needsQuoting(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	for _, ch := range name {
+		if !isWordChar(ch) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func quoteIdentifier(name string) string {
+	if !needsQuoting(name) {
+		return name
+	}
+
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func quoteString(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// Quotes a string for the connection's database. quoteString is only for
+// MySQL, the one that treats backslashes in strings as escapes.
+func quoteLiteral(s string) string {
+	if sqlDialect == dialectMySQL {
+		return quoteString(s)
+	}
+
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// Tables outside the connection's database are qualified with their
+// database name.
+func qualifiedTable(database, table string) string {
+	if database == config.Database {
+		return quoteIdentifier(table)
+	}
+
+	return quoteIdentifier(database) + "." + quoteIdentifier(table)
+}
+
+func queryStrings(query string, args ...interface{}) ([][]string, error) {
+	_, rows, err := fetchStrings(query, args...)
+	return rows, err
+}
+
+// Runs a query and returns its column names and rows as strings, with NULLs
+// shown as "null" like in the results view.
+func fetchStrings(query string, args ...interface{}) ([]string, [][]string,
+						       error) {
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	res, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Close()
+
+	return scanStrings(res)
+}
+
+func scanStrings(res *sql.Rows) ([]string, [][]string, error) {
+	columns, rows, err := scanNullStrings(res)
+	return columns, nullsAs(rows, "null"), err
+}
+
+func scanNullStrings(res *sql.Rows) ([]string, [][]sql.NullString, error) {
+	columns, err := res.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	types, err := res.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kinds := columnKinds(types)
+	rows := [][]sql.NullString {}
+
+	for res.Next() {
+		values := make([]sql.NullString, len(columns))
+
+		if err := res.Scan(textScanners(kinds, values)...); err != nil {
+			return nil, nil, err
+		}
+
+		rows = append(rows, values)
+	}
+
+	return columns, rows, res.Err()
+}
+
+func nullsAs(rows [][]sql.NullString, null string) [][]string {
+	converted := [][]string {}
+
+	for _, values := range rows {
+		row := make([]string, len(values))
+
+		for i, value := range values {
+			row[i] = null
+			if value.Valid {
+				row[i] = value.String
+			}
+		}
+
+		converted = append(converted, row)
+	}
+
+	return converted
+}
+
+func (b *schemaBrowser) load() error {
+	rows, err := queryStrings("SELECT schema_name " +
+				  "FROM information_schema.schemata " +
+				  "ORDER BY schema_name")
+	if err != nil {
+		return err
+	}
+
+	b.roots = []*schemaNode {}
+
+	for _, row := range rows {
+		b.roots = append(b.roots, &schemaNode {
+			kind: schemaDatabase,
+			name: row[0],
+		})
+	}
+
+	b.refresh()
+	return nil
+}
+
+func (n *schemaNode) loadChildren() error {
+	var rows [][]string
+	var err error
+	var kind schemaNodeKind
+
+	switch n.kind {
+	case schemaDatabase:
+		kind = schemaTable
+		rows, err = queryStrings("SELECT table_name, table_type " +
+					 "FROM information_schema.tables " +
+					 "WHERE table_schema = ? " +
+					 "ORDER BY table_name", n.name)
+	case schemaTable:
+		kind = schemaColumn
+		rows, err = queryStrings("SELECT column_name, column_type " +
+					 "FROM information_schema.columns " +
+					 "WHERE table_schema = ? " +
+					 "AND table_name = ? " +
+					 "ORDER BY ordinal_position",
+					 n.parent.name, n.name)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	n.children = []*schemaNode {}
+
+	for _, row := range rows {
+		detail := row[1]
+		if kind == schemaTable && detail == "BASE TABLE" {
+			detail = ""
+		}
+
+		n.children = append(n.children, &schemaNode {
+			kind:   kind,
+			name:   row[0],
+			detail: strings.ToLower(detail),
+			parent: n,
+		})
+	}
+
+	n.loaded = true
+	return nil
+}
+
+func (b *schemaBrowser) flatten(nodes []*schemaNode, depth int) {
+	for _, n := range nodes {
+		marker := "  "
+		if n.kind != schemaColumn {
+			marker = "+ "
+			if n.expanded {
+				marker = "- "
+			}
+		}
+
+		label := strings.Repeat("  ", depth) + marker + n.name
+		if n.detail != "" {
+			label += " " + n.detail
+		}
+
+		b.visible = append(b.visible, n)
+		b.Rows = append(b.Rows, []string {label})
+
+		if n.expanded {
+			b.flatten(n.children, depth + 1)
+		}
+	}
+}
+
+func (b *schemaBrowser) refresh() {
+	b.visible = []*schemaNode {}
+	b.Rows = [][]string {}
+	b.Columns = []tui.Column {
+		{ Name: "Schema", Width: b.Bounds.Width },
+	}
+
+	b.flatten(b.roots, 0)
+
+	if b.SelectedRow >= len(b.visible) {
+		b.SelectedRow = len(b.visible) - 1
+	}
+
+	if b.SelectedRow < 0 {
+		b.SelectedRow = 0
+	}
+}
+
+func (b *schemaBrowser) selected() *schemaNode {
+	if b.SelectedRow < 0 || b.SelectedRow >= len(b.visible) {
+		return nil
+	}
+
+	return b.visible[b.SelectedRow]
+}
+
+func (b *schemaBrowser) toggle(n *schemaNode) {
+	if n.kind == schemaColumn {
+		return
+	}
+
+	if !n.loaded {
+		if err := n.loadChildren(); err != nil {
+			showError(err.Error())
+			return
+		}
+	}
+
+	n.expanded = !n.expanded
+	b.refresh()
+}
+
+// The text inserted into the editor for a node.
+func (n *schemaNode) reference() string {
+	switch n.kind {
+	case schemaTable:
+		return qualifiedTable(n.database(), n.name)
+	}
+
+	return quoteIdentifier(n.name)
+}
+
+func (b *schemaBrowser) HandleEvent(ev escapebox.Event) bool {
+	n := b.selected()
+
+	if ev.Type != termbox.EventKey || n == nil {
+		return b.DetailView.HandleEvent(ev)
+	}
+
+	switch {
+	case ev.Key == termbox.KeyEnter:
+		insertAtCursor(n.reference())
+		return true
+
+	case ev.Key == termbox.KeySpace, ev.Ch == 'l',
+	     ev.Key == termbox.KeyArrowRight:
+		b.toggle(n)
+		return true
+
+	case ev.Ch == 'p':
+		b.preview(n)
+		return true
+
+	case ev.Ch == 'd':
+		b.showCreateTable(n)
+		return true
+
+	case ev.Ch == 'i':
+		if table := n.table(); table != "" {
+			showIndexes(n.database(), table)
+		}
+		return true
+
+	case ev.Ch == 'f':
+		if table := n.table(); table != "" {
+			showForeignKeys(n.database(), table)
+		}
+		return true
+
+	case ev.Ch == 'v':
+		if n.kind == schemaTable {
+			showViewDefinition(n.database(), n.name)
+		}
+		return true
+
+	case ev.Ch == 't':
+		if table := n.table(); table != "" {
+			if err := showTriggers(n.database(), table); err != nil {
+				showError(err.Error())
+			}
+		}
+		return true
+
+	case ev.Ch == 'r':
+		if table := n.table(); table != "" {
+			if err := showDiagram(n.database(), table); err != nil {
+				showError(err.Error())
+			}
+		}
+		return true
+
+	case ev.Ch == 'h', ev.Key == termbox.KeyArrowLeft:
+		if n.expanded {
+			b.toggle(n)
+		} else if n.parent != nil {
+			for i, v := range b.visible {
+				if v == n.parent {
+					b.SelectedRow = i
+				}
+			}
+		}
+		return true
+	}
+
+	return b.DetailView.HandleEvent(ev)
+}
+
+// Shows the first rows of the selected table in the results pane, leaving
+// the editor alone. The LIMIT keeps it from being the full scan EXPLAIN
+// would say it is, so it isn't asked about.
+func (b *schemaBrowser) preview(n *schemaNode) {
+	table := n.table()
+	if table == "" {
+		return
+	}
+
+	runStatement(fmt.Sprintf("SELECT * FROM %s LIMIT %d",
+				 qualifiedTable(n.database(), table),
+				 previewLimit))
+}
+
+func showCreateTable(database, table string) {
+	rows, err := queryStrings("SHOW CREATE TABLE " +
+				  qualifiedTable(database, table))
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		showError(trf("No definition found for %s", table))
+		return
+	}
+
+	showPopup("CREATE TABLE " + table, rows[0][1] + ";\n", true)
+}
+
+func ddlCommand(args []string) error {
+	if len(args) != 1 {
+		return usageError("ddl")
+	}
+
+	showCreateTable(splitTableName(args[0]))
+	return nil
+}
+
+func (b *schemaBrowser) showCreateTable(n *schemaNode) {
+	if table := n.table(); table != "" {
+		showCreateTable(n.database(), table)
+	}
+}
+
+func toggleSidebar() {
+	sidebarVisible = !sidebarVisible
+
+	if sidebarVisible && browser.roots == nil {
+		if err := browser.load(); err != nil {
+			showError(err.Error())
+			sidebarVisible = false
+			return
+		}
+	}
+
+	updateControls()
+	resizeHandler()
+
+	if sidebarVisible {
+		container.Focused = &browser
+	} else if container.Focused == &browser {
+		container.Focused = &editor
+	}
+}
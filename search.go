@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+)
+
+const systemSchemas string = "'information_schema', 'mysql', " +
+			     "'performance_schema', 'sys'"
+
+func init() {
+	registerCommand(command {
+		name:  "search",
+		usage: "[-r] <pattern>",
+		help:  "Find tables and columns (and with -r, routines) by name",
+		run:   searchCommand,
+	})
+}
+
+// Turns a search pattern into a LIKE pattern. * is a wildcard, and patterns
+// without one match anywhere in the name.
+func likePattern(pattern string) string {
+	pattern = strings.Replace(pattern, "_", "\\_", -1)
+	pattern = strings.Replace(pattern, "%", "\\%", -1)
+
+	if !strings.Contains(pattern, "*") {
+		return "%" + pattern + "%"
+	}
+
+	return strings.Replace(pattern, "*", "%", -1)
+}
+
+func searchCommand(args []string) error {
+	routines := len(args) == 2 && args[0] == "-r"
+
+	if len(args) != 1 && !routines {
+		return usageError("search")
+	}
+
+	like := likePattern(args[len(args) - 1])
+
+	query := "SELECT 'table', table_schema, table_name, '' " +
+		 "FROM information_schema.tables " +
+		 "WHERE table_name LIKE ? " +
+		 "AND table_schema NOT IN (" + systemSchemas + ") " +
+		 "UNION ALL " +
+		 "SELECT 'column', table_schema, table_name, column_name " +
+		 "FROM information_schema.columns " +
+		 "WHERE column_name LIKE ? " +
+		 "AND table_schema NOT IN (" + systemSchemas + ")"
+	queryArgs := []interface{} {like, like}
+
+	if routines {
+		query += " UNION ALL " +
+			 "SELECT LOWER(routine_type), routine_schema, " +
+			 "routine_name, '' " +
+			 "FROM information_schema.routines " +
+			 "WHERE (routine_name LIKE ? " +
+			 "OR routine_definition LIKE ?) " +
+			 "AND routine_schema NOT IN (" + systemSchemas + ")"
+		queryArgs = append(queryArgs, like, like)
+	}
+
+	rows, err := queryStrings(query + " ORDER BY 2, 3, 4", queryArgs...)
+	if err != nil {
+		return err
+	}
+
+	showResults([]string {"kind", "database", "table", "column"}, rows)
+	status.Text = trf("%d matches for %s", len(rows),
+			  args[len(args) - 1])
+	return nil
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"errors"
+	"strconv"
+	"strings"
+	"math/rand"
+)
+
+const seedBatchSize int = 100
+const seedNullChance float64 = 0.1
+
+type seedColumn struct {
+	name       string
+	dataType   string
+	columnType string
+	nullable   bool
+	length     int
+	precision  int
+	scale      int
+	options    []string
+
+	// Existing values of the column this one references, if any.
+	references []string
+}
+
+var seedFirstNames = []string {"Ada", "Alan", "Barbara", "Dennis", "Edsger",
+	"Frances", "Grace", "Ken", "Linus", "Margaret", "Niklaus", "Radia"}
+var seedLastNames = []string {"Allen", "Dijkstra", "Hamilton", "Hopper",
+	"Kernighan", "Knuth", "Liskov", "Lovelace", "Perlman", "Ritchie",
+	"Thompson", "Wirth"}
+var seedCities = []string {"Amsterdam", "Austin", "Berlin", "Lagos", "Lima",
+	"Melbourne", "Osaka", "Oslo", "Seoul", "Toronto"}
+var seedWords = []string {"alpha", "bravo", "charlie", "delta", "echo",
+	"foxtrot", "golf", "hotel", "india", "juliet", "kilo", "lima"}
+
+func init() {
+	registerCommand(command {
+		name:  "seed",
+		usage: "<table> <rows> [-x]",
+		help:  "Generate INSERTs of fake rows (-x: run them directly)",
+		run:   seedCommand,
+	})
+}
+
+// Parses the quoted values out of enum('a','b') or set('a','b').
+func parseOptions(columnType string) []string {
+	start := strings.Index(columnType, "(")
+	end := strings.LastIndex(columnType, ")")
+	if start < 0 || end < start {
+		return nil
+	}
+
+	text := []rune(columnType[start + 1:end])
+	options := []string {}
+
+	for _, t := range lex(text, dialectMySQL) {
+		if t.kind != tokenString {
+			continue
+		}
+
+		value := string(text[t.start + 1:t.end - 1])
+		options = append(options, strings.Replace(value, "''", "'", -1))
+	}
+
+	return options
+}
+
+func loadSeedColumns(database, table string) ([]seedColumn, error) {
+	rows, err := queryStrings("SELECT column_name, data_type, " +
+		"column_type, is_nullable, " +
+		"IFNULL(character_maximum_length, 0), " +
+		"IFNULL(numeric_precision, 0), IFNULL(numeric_scale, 0) " +
+		"FROM information_schema.columns " +
+		"WHERE table_schema = ? AND table_name = ? " +
+		"AND extra NOT LIKE '%auto_increment%' " +
+		"AND extra NOT LIKE '%GENERATED%' " +
+		"ORDER BY ordinal_position", database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New(trf("No insertable columns in %s",
+					   table))
+	}
+
+	keys, err := loadForeignKeys(database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []seedColumn {}
+
+	for _, row := range rows {
+		length, _ := strconv.Atoi(row[4])
+		precision, _ := strconv.Atoi(row[5])
+		scale, _ := strconv.Atoi(row[6])
+
+		c := seedColumn {
+			name:       row[0],
+			dataType:   strings.ToLower(row[1]),
+			columnType: strings.ToLower(row[2]),
+			nullable:   row[3] == "YES",
+			length:     length,
+			precision:  precision,
+			scale:      scale,
+		}
+
+		if c.dataType == "enum" || c.dataType == "set" {
+			c.options = parseOptions(row[2])
+		}
+
+		for _, k := range keys {
+			if !k.outgoing(database, table) || k.columns != c.name {
+				continue
+			}
+
+			refs, err := queryStrings(fmt.Sprintf(
+				"SELECT DISTINCT %s FROM %s LIMIT 1000",
+				quoteIdentifier(k.refColumns),
+				qualifiedTable(k.refDatabase, k.refTable)))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, ref := range refs {
+				c.references = append(c.references, ref[0])
+			}
+		}
+
+		columns = append(columns, c)
+	}
+
+	return columns, nil
+}
+
+func pick(values []string) string {
+	return values[rand.Intn(len(values))]
+}
+
+func truncate(s string, length int) string {
+	if length > 0 && len(s) > length {
+		return s[:length]
+	}
+
+	return s
+}
+
+// Guesses at something plausible for a text column from its name.
+func seedText(c seedColumn) string {
+	name := strings.ToLower(c.name)
+	first, last := pick(seedFirstNames), pick(seedLastNames)
+
+	switch {
+	case strings.Contains(name, "email"):
+		return strings.ToLower(first + "." + last) + "@example.com"
+	case strings.Contains(name, "first"):
+		return first
+	case strings.Contains(name, "last"), strings.Contains(name, "surname"):
+		return last
+	case strings.Contains(name, "name"):
+		return first + " " + last
+	case strings.Contains(name, "city"):
+		return pick(seedCities)
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("555-%04d", rand.Intn(10000))
+	case strings.Contains(name, "url"):
+		return "https://example.com/" + pick(seedWords)
+	}
+
+	words := []string {}
+	for i := 0; i < 1 + rand.Intn(4); i++ {
+		words = append(words, pick(seedWords))
+	}
+
+	return strings.Join(words, " ")
+}
+
+func seedInteger(c seedColumn) int64 {
+	max := map[string]int64 {
+		"tinyint":   127,
+		"smallint":  32767,
+		"mediumint": 8388607,
+	}[c.dataType]
+
+	if max == 0 {
+		max = 100000
+	}
+
+	if strings.Contains(c.columnType, "tinyint(1)") {
+		max = 1
+	}
+
+	return rand.Int63n(max + 1)
+}
+
+// A DECIMAL(p, s) has p - s digits before the point, so values are kept
+// below 10^(p - s) to fit. Wide columns are held to the six digits the other
+// numeric types get, and to twelve after the point, to stay within an int64.
+func seedDecimal(c seedColumn) string {
+	digits := c.precision - c.scale
+	if c.precision == 0 || digits > 6 {
+		digits = 6
+	}
+
+	scale := c.scale
+	if scale > 12 {
+		scale = 12
+	}
+
+	limit := int64(1)
+	for i := 0; i < digits + scale; i++ {
+		limit *= 10
+	}
+
+	text := strconv.FormatInt(rand.Int63n(limit), 10)
+	if scale == 0 {
+		return text
+	}
+
+	if len(text) <= scale {
+		text = strings.Repeat("0", scale + 1 - len(text)) + text
+	}
+
+	return text[:len(text) - scale] + "." + text[len(text) - scale:]
+}
+
+// Returns a SQL literal for a random value suited to the column.
+func seedValue(c seedColumn) string {
+	if c.nullable && rand.Float64() < seedNullChance {
+		return "NULL"
+	}
+
+	if len(c.references) > 0 {
+		return quoteString(pick(c.references))
+	}
+
+	when := time.Now().Add(-time.Duration(rand.Int63n(int64(
+		2 * 365 * 24 * time.Hour))))
+
+	switch c.dataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return strconv.FormatInt(seedInteger(c), 10)
+	case "decimal":
+		return seedDecimal(c)
+	case "float", "double", "real":
+		return strconv.FormatFloat(rand.Float64() * 1000, 'f', c.scale, 64)
+	case "bit":
+		return strconv.Itoa(rand.Intn(2))
+	case "date":
+		return quoteString(when.Format("2006-01-02"))
+	case "datetime", "timestamp":
+		return quoteString(when.Format("2006-01-02 15:04:05"))
+	case "time":
+		return quoteString(when.Format("15:04:05"))
+	case "year":
+		return strconv.Itoa(when.Year())
+	case "enum", "set":
+		if len(c.options) > 0 {
+			return quoteString(pick(c.options))
+		}
+	case "json":
+		return quoteString(fmt.Sprintf(`{"%s": %d}`, pick(seedWords),
+					      rand.Intn(100)))
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob",
+	     "longblob":
+		return fmt.Sprintf("X'%08X'", rand.Uint32())
+	}
+
+	return quoteString(truncate(seedText(c), c.length))
+}
+
+func seedStatements(table string, columns []seedColumn, count int) []string {
+	names := []string {}
+	for _, c := range columns {
+		names = append(names, quoteIdentifier(c.name))
+	}
+
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", table,
+			      strings.Join(names, ", "))
+	statements := []string {}
+
+	for done := 0; done < count; done += seedBatchSize {
+		rows := []string {}
+
+		for i := done; i < count && i < done + seedBatchSize; i++ {
+			values := []string {}
+			for _, c := range columns {
+				values = append(values, seedValue(c))
+			}
+
+			rows = append(rows, "  (" + strings.Join(values, ", ") + ")")
+		}
+
+		statements = append(statements,
+				    prefix + strings.Join(rows, ",\n") + ";\n")
+	}
+
+	return statements
+}
+
+func seedCommand(args []string) error {
+	execute := len(args) == 3 && args[2] == "-x"
+
+	if len(args) != 2 && !execute {
+		return usageError("seed")
+	}
+
+	count, err := strconv.Atoi(args[1])
+	if err != nil || count < 1 {
+		return errors.New(tr("The number of rows must be a positive " +
+				     "number"))
+	}
+
+	database, table := splitTableName(args[0])
+
+	columns, err := loadSeedColumns(database, table)
+	if err != nil {
+		return err
+	}
+
+	statements := seedStatements(qualifiedTable(database, table), columns,
+				     count)
+
+	if !execute {
+		insertAtCursor(strings.Join(statements, ""))
+		showMessage(trf("Generated %d rows for %s", count,
+				table))
+		return nil
+	}
+
+	for _, s := range statements {
+		ctx, cancel := queryContext()
+		_, err := execStatement(ctx, db, s)
+		cancel()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	showMessage(trf("Inserted %d rows into %s", count, table))
+	return nil
+}
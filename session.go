@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// Where a connection's session left off, restored the next time prequel
+// connects to it.
+type sessionState struct {
+	Cursor int       `json:"cursor"`
+	Query  string    `json:"query,omitempty"`
+	Rows   int       `json:"rows,omitempty"`
+	RanAt  time.Time `json:"ran_at"`
+}
+
+func profileFlagGiven() bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "profile" {
+			given = true
+		}
+	})
+
+	return given
+}
+
+// Without --profile, reconnects with the profile used last, if it still
+// exists.
+func restoreProfile() {
+	if profileFlagGiven() || demoMode || state.Profile == "" {
+		return
+	}
+
+	if _, ok := config.Profiles[state.Profile]; ok {
+		profileName = state.Profile
+	}
+}
+
+func currentSession() sessionState {
+	return state.Sessions[config.Connection.slug()]
+}
+
+func updateSession(update func(*sessionState)) {
+	if state.Sessions == nil {
+		state.Sessions = map[string]sessionState {}
+	}
+
+	session := currentSession()
+	update(&session)
+	state.Sessions[config.Connection.slug()] = session
+}
+
+// Called for each statement run into the results view.
+func rememberResult(query string, rows int) {
+	updateSession(func(s *sessionState) {
+		s.Query = query
+		s.Rows = rows
+		s.RanAt = time.Now()
+	})
+}
+
+// Puts the cursor back and says what was run last. The statement isn't
+// re-run, since it might change data.
+func restoreSession() {
+	session := currentSession()
+
+	cursor := session.Cursor
+	if length := len([]rune(editor.GetText())); cursor > length {
+		cursor = length
+	}
+	editor.SetCursor(cursor)
+
+	if session.Query != "" {
+		showMessage(trf("Last session: %s (%d rows, %s)",
+				abbreviate(session.Query), session.Rows,
+				session.RanAt.Format("2006-01-02 15:04")))
+	}
+}
+
+func saveSession() {
+	updateSession(func(s *sessionState) {
+		s.Cursor = editor.GetCursor()
+	})
+
+	if !demoMode {
+		state.Profile = profileName
+	}
+
+	saveState()
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"bufio"
+	"errors"
+	"strings"
+	"time"
+	"sync/atomic"
+)
+
+// Reads a script one statement at a time, so dumps too big to comfortably
+// load into the editor can still be run.
+type scriptReader struct {
+	reader    *bufio.Reader
+	buffer    []rune
+	delimiter string
+	read      int64
+	eof       bool
+
+	// The buffer lexed and split so far. Statements and their tokens are
+	// dropped from the front as they're returned, up to consumed.
+	tokens     []token
+	statements []Statement
+	consumed   int
+}
+
+// Set to stop the script being run by source after its current statement.
+var sourceAborted int32
+
+var sourceRunning bool
+
+func init() {
+	registerCommand(command {
+		name:  "source",
+		usage: "[-f] <file>",
+		help:  "Run a SQL script (-f: keep going after errors)",
+		run:   sourceCommand,
+	})
+
+	registerCommand(command {
+		name: "abort",
+		help: "Stop the script being run by source",
+		run:  abortCommand,
+	})
+}
+
+func newScriptReader(r io.Reader) *scriptReader {
+	return &scriptReader {
+		reader:    bufio.NewReader(r),
+		delimiter: defaultDelimiter,
+	}
+}
+
+// Returns the next statement to send to the server, or io.EOF at the end
+// of the script. Directives like DELIMITER are handled here and skipped.
+func (s *scriptReader) next() (string, error) {
+	for {
+		// A statement is known to be complete once another one starts
+		// after it; the last one is only complete at the end of the file.
+		if len(s.statements) > 1 || s.eof && len(s.statements) == 1 {
+			first := s.statements[0]
+			s.statements = s.statements[1:]
+
+			query := statementQuery(s.buffer, first)
+			empty := s.dropTokens(first)
+
+			s.consumed = first.start + first.length
+			s.delimiter = first.delimiter
+
+			if first.directive || empty {
+				continue
+			}
+
+			return query, nil
+		}
+
+		if s.eof {
+			return "", io.EOF
+		}
+
+		s.compact()
+		lexed := len(s.buffer)
+
+		if err := s.readUntilDelimiter(); err != nil {
+			return "", err
+		}
+
+		s.relex(lexed)
+	}
+}
+
+// Drops the tokens of a statement being returned, returning whether they
+// were all whitespace and comments. One that runs on into the next
+// statement is kept.
+func (s *scriptReader) dropTokens(statement Statement) bool {
+	end := statement.start + statement.length
+	empty := true
+
+	for len(s.tokens) > 0 && s.tokens[0].start < end {
+		t := s.tokens[0]
+		if t.kind != tokenWhitespace && t.kind != tokenComment {
+			empty = false
+		}
+
+		if t.end > end {
+			break
+		}
+
+		s.tokens = s.tokens[1:]
+	}
+
+	return empty
+}
+
+// Drops the text of the statements already returned once it's at least
+// half the buffer, so it's only moved a few times however long it gets.
+func (s *scriptReader) compact() {
+	if s.consumed == 0 || s.consumed * 2 < len(s.buffer) {
+		return
+	}
+
+	s.buffer = s.buffer[:copy(s.buffer, s.buffer[s.consumed:])]
+
+	for i := range s.tokens {
+		s.tokens[i].start -= s.consumed
+		s.tokens[i].end -= s.consumed
+
+		if s.tokens[i].start < 0 {
+			s.tokens[i].start = 0
+		}
+	}
+
+	for i := range s.statements {
+		s.statements[i].start -= s.consumed
+	}
+
+	s.consumed = 0
+}
+
+// Lexes the lines read since the buffer was lexed up to lexed, and splits
+// the statement they continue again. Lines are read whole, so only the
+// last token before them can run on into them.
+func (s *scriptReader) relex(lexed int) {
+	if len(s.tokens) > 0 && s.tokens[len(s.tokens) - 1].end == lexed {
+		s.tokens = s.tokens[:len(s.tokens) - 1]
+	}
+
+	l := lexerAfter(s.buffer, sqlDialect, s.tokens)
+	if l.pos < s.consumed {
+		l.pos = s.consumed
+	}
+
+	for l.pos < len(s.buffer) {
+		s.tokens = append(s.tokens, l.next())
+	}
+
+	s.statements = splitRange(s.buffer, s.tokens, s.consumed,
+				  len(s.buffer), s.delimiter)
+}
+
+// Reads lines until one could end a statement, to avoid re-lexing the
+// buffer for every line of a long one.
+func (s *scriptReader) readUntilDelimiter() error {
+	for {
+		line, err := s.reader.ReadString('\n')
+		s.read += int64(len(line))
+		s.buffer = append(s.buffer, []rune(line)...)
+
+		if err == io.EOF {
+			s.eof = true
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		trimmed := strings.TrimSpace(line)
+
+		if strings.Contains(line, s.delimiter) ||
+		   strings.HasPrefix(strings.ToUpper(trimmed), "DELIMITER") {
+			return nil
+		}
+	}
+}
+
+func abbreviate(query string) string {
+	return truncateWidth(strings.Join(strings.Fields(query), " "), 63)
+}
+
+// Runs on a single connection so USE and SET statements apply to the rest
+// of the script.
+func runScript(path string, force bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		showError(err.Error())
+		return
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		showError(err.Error())
+		return
+	}
+
+	sourceRunning = true
+	showQueryState(true)
+	atomic.StoreInt32(&sourceAborted, 0)
+	showProgress(trf("Running %s  (run abort to stop it)", path))
+	started := time.Now()
+
+	go func() {
+		defer recoverCrash()
+		defer file.Close()
+
+		count, failures, err := executeScript(newScriptReader(file),
+						      info.Size(), force)
+
+		post(func() {
+			sourceRunning = false
+			showQueryState(false)
+			sourceFinished(path, count, failures, err)
+			notifyIfLong("Script finished", started)
+		})
+	}()
+}
+
+func executeScript(script *scriptReader, size int64,
+		   force bool) (int, []string, error) {
+	conn, err := db.Conn(appContext)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer conn.Close()
+
+	count := 0
+	failures := []string {}
+	reported := time.Now()
+
+	for atomic.LoadInt32(&sourceAborted) == 0 {
+		query, err := script.next()
+		if err == io.EOF {
+			return count, failures, nil
+		}
+
+		if err != nil {
+			return count, failures, err
+		}
+
+		count++
+
+		ctx, cancel := queryContext()
+		_, err = execStatement(ctx, conn, query)
+		cancel()
+
+		if err != nil {
+			failure := fmt.Sprintf("Statement %d (%s): %s", count,
+					       abbreviate(query), err)
+
+			if !force {
+				return count, failures, errors.New(failure)
+			}
+
+			failures = append(failures, failure)
+		}
+
+		// Posting after every statement would flood the UI.
+		if time.Since(reported) < 100 * time.Millisecond {
+			continue
+		}
+		reported = time.Now()
+
+		percent := 100
+		if size > 0 {
+			percent = int(script.read * 100 / size)
+		}
+
+		done := count
+		post(func() {
+			showProgress(trf("Running script: %d%%, %d " +
+					 "statements", percent, done))
+		})
+	}
+
+	return count, failures, errors.New(tr("Aborted"))
+}
+
+func sourceFinished(path string, count int, failures []string, err error) {
+	if len(failures) > 0 {
+		showPopup(tr("Script errors"), strings.Join(failures, "\n"), false)
+	}
+
+	clearProgress()
+
+	switch {
+	case err != nil:
+		showToast(trf("%s stopped after %d statements: %s",
+			      path, count, err), true)
+	case len(failures) > 0:
+		showToast(trf("Ran %d statements from %s, %d failed",
+			      count, path, len(failures)), true)
+	default:
+		showToast(trf("Ran %d statements from %s", count, path),
+			  false)
+	}
+}
+
+func sourceCommand(args []string) error {
+	force := len(args) == 2 && args[0] == "-f"
+	if force {
+		args = args[1:]
+	}
+
+	if len(args) != 1 {
+		return usageError("source")
+	}
+
+	if sourceRunning {
+		return errors.New(tr("A script is already running"))
+	}
+
+	runScript(args[0], force)
+	return nil
+}
+
+func abortCommand(args []string) error {
+	if !sourceRunning {
+		return errors.New(tr("No script is running"))
+	}
+
+	atomic.StoreInt32(&sourceAborted, 1)
+	return nil
+}
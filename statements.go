@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+const defaultDelimiter string = ";"
+
+type Statement struct {
+	start     int
+	length    int
+	delimiter string
+
+	// Client-side directives like DELIMITER aren't sent to the server.
+	directive bool
+}
+
+func cursorInWhichStatement(cur int, ss []Statement) (Statement, error) {
+	for _, s := range ss {
+		if cur > s.start + s.length - 1 {
+			continue
+		}
+
+		return s, nil
+	}
+
+	// Default to last statement if there is one
+	if len(ss) > 0 {
+		return ss[len(ss) - 1], nil
+	}
+
+	return Statement {}, errors.New(tr("Cursor not in statement"))
+}
+
+func hasPrefixAt(text []rune, i int, prefix string) bool {
+	for _, ch := range prefix {
+		if i >= len(text) || text[i] != ch {
+			return false
+		}
+		i++
+	}
+
+	return true
+}
+
+func lineEnd(text []rune, i int) int {
+	for i < len(text) && text[i] != '\n' {
+		i++
+	}
+
+	return i
+}
+
+// Splits text into statements. Statements end at the current delimiter
+// (outside of quotes, identifiers and comments) and EOF. Like the mysql
+// client, a DELIMITER line at the start of a statement changes the
+// delimiter for the statements after it.
+func splitStatements(text []rune, tokens []token) []Statement {
+	return splitStatementsFrom(text, tokens, defaultDelimiter)
+}
+
+// Like splitStatements, but starting with a delimiter other than the
+// default, for text that continues an earlier script.
+func splitStatementsFrom(text []rune, tokens []token,
+			 delimiter string) []Statement {
+	statements := []Statement {}
+	statementStart := 0
+	empty := true
+
+	// Statements should include a trailing newline if present.
+	endStatement := func(end int, directive bool) int {
+		if end < len(text) && text[end] == '\n' {
+			end++
+		}
+
+		statements = append(statements, Statement {
+			start: statementStart,
+			length: end - statementStart,
+			delimiter: delimiter,
+			directive: directive,
+		})
+
+		statementStart = end
+		empty = true
+
+		return end
+	}
+
+	pos := 0
+
+	for _, t := range tokens {
+		if t.end <= pos {
+			continue
+		}
+
+		switch t.kind {
+		case tokenWhitespace, tokenComment:
+			continue
+
+		case tokenString, tokenIdentifier:
+			empty = false
+			continue
+
+		case tokenWord:
+			word := string(text[t.start:t.end])
+
+			if empty && strings.EqualFold(word, "DELIMITER") {
+				end := lineEnd(text, t.end)
+				fields := strings.Fields(string(text[t.end:end]))
+
+				if len(fields) > 0 {
+					delimiter = fields[0]
+				}
+
+				pos = endStatement(end, true)
+				continue
+			}
+		}
+
+		empty = false
+
+		for i := t.start; i < t.end; i++ {
+			if i >= pos && hasPrefixAt(text, i, delimiter) {
+				pos = endStatement(i + len([]rune(delimiter)), false)
+				break
+			}
+		}
+	}
+
+	if statementStart < len(text) {
+		endStatement(len(text), false)
+	}
+
+	return statements
+}
+
+// Returns the text of the statement that should be sent to the server. The
+// default delimiter is passed through, but custom ones are stripped. A
+// DELIMITER line isn't ended by a delimiter, so it's left whole.
+func statementQuery(text []rune, s Statement) string {
+	end := s.start + s.length
+	if end > len(text) {
+		end = len(text)
+	}
+
+	if s.start >= end {
+		return ""
+	}
+
+	query := string(text[s.start:end])
+
+	if s.delimiter != defaultDelimiter && !s.directive {
+		query = strings.TrimSpace(query)
+		query = strings.TrimSuffix(query, s.delimiter)
+	}
+
+	return query
+}
+
+// Splits text[from:to] into statements, as if it ran on from the delimiter
+// given. tokens has to cover the text from from on; one starting before it
+// is cut there.
+func splitRange(text []rune, tokens []token, from, to int,
+		delimiter string) []Statement {
+	part := []token {}
+	for _, t := range tokens {
+		if t.start >= to {
+			break
+		}
+
+		if t.end <= from {
+			continue
+		}
+
+		if t.start < from {
+			t.start = from
+		}
+		if t.end > to {
+			t.end = to
+		}
+		t.start -= from
+		t.end -= from
+
+		part = append(part, t)
+	}
+
+	statements := splitStatementsFrom(text[from:to], part, delimiter)
+	for i := range statements {
+		statements[i].start += from
+	}
+
+	return statements
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Splits text and returns each statement as it would be sent, trimmed, with
+// directives written as "directive: <text>".
+func splitForTest(text string, d dialect) []string {
+	runes := []rune(text)
+	queries := []string {}
+
+	for _, s := range splitStatements(runes, lex(runes, d)) {
+		query := strings.TrimSpace(statementQuery(runes, s))
+		if s.directive {
+			query = "directive: " + query
+		}
+
+		queries = append(queries, query)
+	}
+
+	return queries
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect dialect
+		text    string
+		want    []string
+	} {
+		{
+			name: "default delimiter",
+			text: "select 1;\nselect 2;\n",
+			want: []string {"select 1;", "select 2;"},
+		},
+		{
+			name: "no terminator on the last statement",
+			text: "select 1;\nselect 2",
+			want: []string {"select 1;", "select 2"},
+		},
+		{
+			name: "delimiter in quotes",
+			text: "select ';', \";\", `a;b`;\nselect 2;",
+			want: []string {"select ';', \";\", `a;b`;",
+					"select 2;"},
+		},
+		{
+			name: "delimiter in a line comment",
+			text: "select 1 -- not; the end\n, 2;\nselect 3;",
+			want: []string {"select 1 -- not; the end\n, 2;",
+					"select 3;"},
+		},
+		{
+			name: "delimiter in a hash comment",
+			text: "select 1 # not; the end\n;\nselect 2;",
+			want: []string {"select 1 # not; the end\n;",
+					"select 2;"},
+		},
+		{
+			name: "delimiter in a block comment",
+			text: "select /* a; b */ 1;\nselect 2;",
+			want: []string {"select /* a; b */ 1;", "select 2;"},
+		},
+		{
+			name: "DELIMITER changes the delimiter",
+			text: "DELIMITER //\n" +
+			      "create procedure p() begin select 1; end//\n" +
+			      "DELIMITER ;\n" +
+			      "select 2;",
+			want: []string {
+				"directive: DELIMITER //",
+				"create procedure p() begin select 1; end",
+				"directive: DELIMITER ;",
+				"select 2;",
+			},
+		},
+		{
+			name: "DELIMITER only counts starting a statement",
+			text: "select delimiter from t;\nselect 2;",
+			want: []string {"select delimiter from t;",
+					"select 2;"},
+		},
+		{
+			name:    "dollar quotes",
+			dialect: dialectPostgres,
+			text:    "create function f() returns int as $$ " +
+				 "select 1; $$ language sql;\nselect 2;",
+			want:    []string {"create function f() returns int " +
+					   "as $$ select 1; $$ language sql;",
+					   "select 2;"},
+		},
+		{
+			name:    "tagged dollar quotes",
+			dialect: dialectPostgres,
+			text:    "select $a$ $$; $a$;\nselect 2",
+			want:    []string {"select $a$ $$; $a$;", "select 2"},
+		},
+		{
+			name: "empty text",
+			text: "",
+			want: []string {},
+		},
+	}
+
+	for _, test := range tests {
+		got := splitForTest(test.text, test.dialect)
+
+		if strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("%s: got %q, want %q", test.name, got,
+				 test.want)
+		}
+	}
+}
+
+func TestSplitStatementsCoversText(t *testing.T) {
+	text := []rune("select 1; -- a\nselect 2;\n\nselect 3")
+	statements := splitStatements(text, lex(text, dialectMySQL))
+
+	end := 0
+	for _, s := range statements {
+		if s.start != end {
+			t.Fatalf("statement starts at %d, want %d", s.start,
+				 end)
+		}
+
+		end = s.start + s.length
+	}
+
+	if end != len(text) {
+		t.Errorf("statements end at %d, want %d", end, len(text))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"errors"
+	"time"
+	"strings"
+)
+
+// Go layouts for the strftime directives the date_format setting supports.
+var strftimeLayouts = map[byte]string {
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'e': "_2",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'p': "PM",
+	'b': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'Z': "MST",
+	'z': "-0700",
+	'F': "2006-01-02",
+	'T': "15:04:05",
+	'R': "15:04",
+}
+
+// Each directive is formatted on its own, so literal text in the format
+// can't be mistaken for part of a Go layout.
+func strftime(t time.Time, format string) string {
+	var out strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i + 1 == len(format) {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		directive := format[i]
+
+		switch {
+		case directive == '%':
+			out.WriteByte('%')
+		case directive == 'j':
+			fmt.Fprintf(&out, "%03d", t.YearDay())
+		case directive == 'f':
+			fmt.Fprintf(&out, "%06d", t.Nanosecond() / 1000)
+		case strftimeLayouts[directive] != "":
+			out.WriteString(t.Format(strftimeLayouts[directive]))
+		default:
+			out.WriteByte('%')
+			out.WriteByte(directive)
+		}
+	}
+
+	return out.String()
+}
+
+// Directives for the time of day, which DATE values don't have.
+const timeDirectives string = "HIMSpfTRZz"
+
+// The format up to the last directive before the time of day, for DATE
+// values, or the whole format if it starts with the time.
+func dateOnly(format string) string {
+	end := 0
+
+	for i := 0; i + 1 < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		if strings.IndexByte(timeDirectives, format[i + 1]) >= 0 {
+			break
+		}
+
+		i++
+		end = i + 1
+	}
+
+	if end == 0 {
+		return format
+	}
+
+	return format[:end]
+}
+
+func checkDateFormat(format string) error {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		i++
+		if i == len(format) {
+			return errors.New(tr("The date_format setting ends " +
+					     "in %"))
+		}
+
+		directive := format[i]
+		if directive != '%' && directive != 'j' && directive != 'f' &&
+		   strftimeLayouts[directive] == "" {
+			return errors.New(trf("Unknown directive %%%c in " +
+					      "date_format", directive))
+		}
+	}
+
+	return nil
+}
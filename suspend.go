@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"bufio"
+	"os/exec"
+	"strings"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+	"github.com/briansteffens/tui"
+)
+
+const defaultPager string = "less"
+
+// Hands the terminal to another program, then takes it back and redraws.
+func suspend(run func() error) error {
+	disableBracketedPaste()
+	disableFocusReporting()
+	tui.Close()
+
+	err := run()
+
+	tui.Init()
+	termbox.SetInputMode(inputMode)
+	enableTruecolor()
+	enableBracketedPaste()
+	enableFocusReporting()
+
+	// Wake the main loop so it redraws over whatever the program left.
+	termbox.Interrupt()
+	return err
+}
+
+// Shows text in $PAGER (less by default) instead of the popup viewer.
+func page(text string) {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+
+	err := suspend(func() error {
+		cmd := exec.Command("sh", "-c", pager)
+		cmd.Stdin = strings.NewReader(text)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	})
+
+	if err != nil {
+		showError(trf("Pager: %s", err))
+	}
+}
+
+// Every value of the selected row, one column per line, untruncated.
+func selectedRowText() string {
+	if results.SelectedRow >= len(results.Rows) {
+		return ""
+	}
+
+	row := results.Rows[results.SelectedRow]
+	lines := []string {}
+
+	for i := range results.Columns {
+		if i < len(row) {
+			lines = append(lines, columnName(i) + ": " +
+					     cellValue(results.SelectedRow, i))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func handleResultsPagerEvent(ev escapebox.Event) bool {
+	if ev.Type != termbox.EventKey || ev.Ch != '|' ||
+	   len(results.Rows) == 0 {
+		return false
+	}
+
+	page(selectedRowText())
+	return true
+}
+
+// Runs a shell command on the terminal, like psql's \!. The output stays
+// up until Enter is pressed. With no command, starts an interactive shell.
+func shellEscape(command string) error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+
+	return suspend(func() error {
+		cmd := exec.Command(shell)
+		if command != "" {
+			cmd = exec.Command(shell, "-c", command)
+		}
+
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+
+		if command != "" {
+			fmt.Print("\nPress Enter to return to prequel")
+			bufio.NewReader(os.Stdin).ReadString('\n')
+		}
+
+		return err
+	})
+}
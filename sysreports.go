@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+type sysReport struct {
+	name  string
+	help  string
+	query string
+}
+
+var sysReports = []sysReport {
+	{"statements", "Top statements by total latency",
+	 "SELECT query, db, exec_count, total_latency, avg_latency, " +
+	 "rows_examined_avg FROM sys.statement_analysis LIMIT 50"},
+	{"full-scans", "Statements doing full table scans",
+	 "SELECT query, db, exec_count, total_latency, no_index_used_count, " +
+	 "rows_sent_avg FROM sys.statements_with_full_table_scans LIMIT 50"},
+	{"unused-indexes", "Indexes not used since the server started",
+	 "SELECT object_schema, object_name, index_name " +
+	 "FROM sys.schema_unused_indexes"},
+	{"redundant-indexes", "Indexes covered by another index",
+	 "SELECT table_schema, table_name, redundant_index_name, " +
+	 "dominant_index_name, sql_drop_index " +
+	 "FROM sys.schema_redundant_indexes"},
+	{"table-io", "Tables by total IO latency",
+	 "SELECT table_schema, table_name, total_latency, rows_fetched, " +
+	 "rows_inserted, rows_updated, rows_deleted " +
+	 "FROM sys.schema_table_statistics LIMIT 50"},
+	{"file-io", "Files by IO latency",
+	 "SELECT file, total, total_latency, read_latency, write_latency " +
+	 "FROM sys.io_global_by_file_by_latency LIMIT 50"},
+	{"waits", "Wait events by total latency",
+	 "SELECT events, total, total_latency, avg_latency " +
+	 "FROM sys.waits_global_by_latency LIMIT 50"},
+	{"memory", "Memory use by allocation type",
+	 "SELECT event_name, current_count, current_alloc " +
+	 "FROM sys.memory_global_by_current_bytes LIMIT 50"},
+	{"users", "Activity per user",
+	 "SELECT user, statements, statement_latency, table_scans, " +
+	 "current_connections, total_connections " +
+	 "FROM sys.user_summary"},
+}
+
+func init() {
+	registerCommand(command {
+		name:  "sys",
+		usage: "[report]",
+		help:  "Run a sys schema report, or list them",
+		run:   sysCommand,
+	})
+}
+
+func showSysMenu() {
+	rows := [][]string {}
+	for i, report := range sysReports {
+		rows = append(rows, []string {strconv.Itoa(i + 1), report.name,
+					      report.help})
+	}
+
+	showResults([]string {"key", "report", "description"}, rows)
+	resultsView = "sys"
+	status.Text = tr("Press a number or Enter to run a report")
+}
+
+func runSysReport(report sysReport) error {
+	columns, rows, err := fetchStrings(report.query)
+	if err != nil {
+		return err
+	}
+
+	showResults(columns, rows)
+	status.Text = trf("%s: %d rows", report.help, len(rows))
+	return nil
+}
+
+func sysCommand(args []string) error {
+	switch len(args) {
+	case 0:
+		showSysMenu()
+		return nil
+	case 1:
+		for _, report := range sysReports {
+			if report.name == args[0] {
+				return runSysReport(report)
+			}
+		}
+
+		return errors.New(trf("No sys report named '%s'", args[0]))
+	}
+
+	return usageError("sys")
+}
+
+func handleSysMenuEvent(ev escapebox.Event) bool {
+	if resultsView != "sys" || ev.Type != termbox.EventKey {
+		return false
+	}
+
+	index := -1
+
+	switch {
+	case ev.Key == termbox.KeyEnter:
+		index = results.SelectedRow
+	case ev.Ch >= '1' && ev.Ch <= '9':
+		index = int(ev.Ch - '1')
+	default:
+		return false
+	}
+
+	if index >= 0 && index < len(sysReports) {
+		if err := runSysReport(sysReports[index]); err != nil {
+			showError(err.Error())
+		}
+	}
+
+	return true
+}
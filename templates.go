@@ -0,0 +1,250 @@
+package main
+
+import (
+	"time"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A :name or :name:type placeholder in a macro step or library query.
+type placeholder struct {
+	name string
+	kind string
+	span [2]int
+}
+
+// Numbers as SQL writes them. ParseFloat also takes things like NaN, Inf
+// and hex floats, which would end up in the SQL unquoted.
+var plainNumber = regexp.MustCompile(`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)$`)
+
+// What each placeholder type accepts, shown when asking for a value.
+var placeholderKinds = map[string]string {
+	"date":     "YYYY-MM-DD",
+	"datetime": "YYYY-MM-DD HH:MM:SS",
+	"int":      "a whole number",
+	"number":   "a number",
+	"string":   "text",
+}
+
+var datetimeLayouts = []string {
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// Finds the placeholders in text, outside of strings and comments.
+func findPlaceholders(text []rune) []placeholder {
+	tokens := lex(text, sqlDialect)
+	found := []placeholder {}
+
+	isColon := func(t token) bool {
+		return t.kind == tokenSymbol && text[t.start] == ':'
+	}
+
+	// Whether tokens i and i + 1 are a colon and a word right after it.
+	colonWord := func(i int) bool {
+		return i + 1 < len(tokens) && isColon(tokens[i]) &&
+		       tokens[i + 1].kind == tokenWord &&
+		       tokens[i + 1].start == tokens[i].end
+	}
+
+	for i := 0; i + 1 < len(tokens); i++ {
+		colon, name := tokens[i], tokens[i + 1]
+
+		if !colonWord(i) {
+			continue
+		}
+
+		// Skip Postgres-style casts like value::int.
+		if colon.start > 0 && text[colon.start - 1] == ':' {
+			continue
+		}
+
+		p := placeholder {
+			name: string(text[name.start:name.end]),
+			span: [2]int {colon.start, name.end},
+		}
+
+		// A type follows after a single colon (two would be a cast).
+		if colonWord(i + 2) && tokens[i + 2].start == name.end {
+			kind := strings.ToLower(string(
+				text[tokens[i + 3].start:tokens[i + 3].end]))
+
+			if _, ok := placeholderKinds[kind]; ok {
+				p.kind = kind
+				p.span[1] = tokens[i + 3].end
+				i += 2
+			}
+		}
+
+		found = append(found, p)
+		i++
+	}
+
+	return found
+}
+
+// Every placeholder across the texts, in order of first use. A type given
+// on any use of a name applies to all of them.
+func placeholderParams(texts []string) []placeholder {
+	params := []placeholder {}
+	index := map[string]int {}
+
+	for _, text := range texts {
+		for _, p := range findPlaceholders([]rune(text)) {
+			i, seen := index[p.name]
+			if !seen {
+				index[p.name] = len(params)
+				params = append(params, p)
+			} else if params[i].kind == "" {
+				params[i].kind = p.kind
+			}
+		}
+	}
+
+	return params
+}
+
+func paramKinds(params []placeholder) map[string]string {
+	kinds := map[string]string {}
+	for _, p := range params {
+		kinds[p.name] = p.kind
+	}
+
+	return kinds
+}
+
+// Checks a value against its placeholder's type, returning it in the form
+// the server expects.
+func validatePlaceholder(p placeholder, value string) (string, error) {
+	if p.kind == "" || p.kind == "string" {
+		return value, nil
+	}
+
+	value = strings.TrimSpace(value)
+
+	switch p.kind {
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", errors.New(trf("%s must be a whole number",
+						  p.name))
+		}
+
+	case "number":
+		if !plainNumber.MatchString(value) {
+			return "", errors.New(trf("%s must be a number", p.name))
+		}
+
+	case "date":
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return "", errors.New(trf("%s must be a date like %s",
+						  p.name, "2024-01-31"))
+		}
+		value = parsed.Format("2006-01-02")
+
+	case "datetime":
+		for _, layout := range datetimeLayouts {
+			if parsed, err := time.Parse(layout, value); err == nil {
+				return parsed.Format("2006-01-02 15:04:05"), nil
+			}
+		}
+
+		return "", errors.New(trf("%s must be a date and time like %s",
+					  p.name, "2024-01-31 09:30"))
+	}
+
+	return value, nil
+}
+
+// Fills in the placeholders. Values are quoted in SQL, the way the
+// connection's database expects, unless they're plain numbers (or typed as
+// one), and passed as they are to commands.
+func fillPlaceholders(step string, values map[string]string,
+		      kinds map[string]string) string {
+	text := []rune(step)
+	found := findPlaceholders(text)
+	command := isCommandStep(step)
+
+	for i := len(found) - 1; i >= 0; i-- {
+		p := found[i]
+		value := values[p.name]
+
+		kind := kinds[p.name]
+		if kind == "" {
+			kind = p.kind
+		}
+
+		numeric := kind == "int" || kind == "number"
+		if kind == "" {
+			numeric = plainNumber.MatchString(value)
+		}
+
+		if !command && !numeric {
+			value = quoteLiteral(value)
+		}
+
+		text = append(text[:p.span[0]],
+			      append([]rune(value), text[p.span[1]:]...)...)
+	}
+
+	return string(text)
+}
+
+func placeholderLabel(p placeholder) string {
+	if p.kind == "" {
+		return p.name + ": "
+	}
+
+	return trf("%s (%s): ", p.name, tr(placeholderKinds[p.kind]))
+}
+
+// Asks for each value not already given, one at a time, asking again
+// until it's valid for its type, then calls done.
+func askForPlaceholders(params []placeholder, values map[string]string,
+			done func()) {
+	for _, p := range params {
+		if _, ok := values[p.name]; ok {
+			continue
+		}
+
+		p := p
+		askFor(placeholderLabel(p), func(answer string) {
+			value, err := validatePlaceholder(p, answer)
+			if err != nil {
+				askForPlaceholders(params, values, done)
+				status.Text = err.Error() + " - " + status.Text
+				return
+			}
+
+			values[p.name] = value
+			askForPlaceholders(params, values, done)
+		})
+		return
+	}
+
+	done()
+}
+
+// Checks values given up front, like a macro's arguments.
+func validatePlaceholders(params []placeholder,
+			  values map[string]string) error {
+	for _, p := range params {
+		value, ok := values[p.name]
+		if !ok {
+			continue
+		}
+
+		value, err := validatePlaceholder(p, value)
+		if err != nil {
+			return err
+		}
+
+		values[p.name] = value
+	}
+
+	return nil
+}
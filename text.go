@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"github.com/mattn/go-runewidth"
+)
+
+// The number of terminal columns s takes up, counting wide characters like
+// CJK and most emoji as two.
+func displayWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// Whether termbox draws r across two cells. It draws ambiguous characters
+// in one, whatever the locale.
+func isWide(r rune) bool {
+	return runewidth.RuneWidth(r) == 2 && !runewidth.IsAmbiguousWidth(r)
+}
+
+func padRight(s string, width int, fill string) string {
+	if displayWidth(s) >= width {
+		return s
+	}
+
+	return s + strings.Repeat(fill, width - displayWidth(s))
+}
+
+// Shortens s to at most width columns, ending it with "..." if anything
+// was cut. Never splits a character.
+func truncateWidth(s string, width int) string {
+	return runewidth.Truncate(s, width, "...")
+}
+
+// Writes n with commas between groups of three digits, like 120,000.
+func groupThousands(n int) string {
+	return groupDigits(strconv.Itoa(n))
+}
+
+// Puts commas in the whole part of a decimal number written out as text,
+// like -1234.5678 or 1234, leaving anything else alone.
+func groupDigits(number string) string {
+	sign, digits, fraction := splitDecimal(number)
+	if digits == "" {
+		return number
+	}
+
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+
+	return sign + digits + fraction
+}
+
+// Splits a number into its sign, whole part and the rest from the decimal
+// point on. The whole part is empty if it isn't a plain decimal number.
+func splitDecimal(number string) (string, string, string) {
+	sign := ""
+	if strings.HasPrefix(number, "-") || strings.HasPrefix(number, "+") {
+		sign, number = number[:1], number[1:]
+	}
+
+	digits, fraction := number, ""
+	if dot := strings.IndexByte(number, '.'); dot >= 0 {
+		digits, fraction = number[:dot], number[dot:]
+	}
+
+	if digits == "" || strings.Trim(digits, "0123456789") != "" ||
+	   strings.Trim(fraction, ".0123456789") != "" {
+		return "", "", ""
+	}
+
+	return sign, digits, fraction
+}
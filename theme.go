@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"io/ioutil"
+	"path/filepath"
+	"encoding/json"
+	"github.com/nsf/termbox-go"
+)
+
+type Theme struct {
+	Text       termbox.Attribute
+	Keyword    termbox.Attribute
+	Type       termbox.Attribute
+	Function   termbox.Attribute
+	String     termbox.Attribute
+	Number     termbox.Attribute
+	Identifier termbox.Attribute
+	Comment    termbox.Attribute
+	Background termbox.Attribute
+	Statement  termbox.Attribute
+	RowBg      termbox.Attribute
+	RowBgAlt   termbox.Attribute
+	SelectedBg termbox.Attribute
+	Error      termbox.Attribute
+	Warning    termbox.Attribute
+	StatusText termbox.Attribute
+	StatusBg   termbox.Attribute
+}
+
+// A theme in its own file, changing some colors of a built-in one.
+type themeFile struct {
+	Base   string            `json:"base"`
+	Colors map[string]string `json:"colors"`
+}
+
+const defaultThemeName string = "default"
+
+var themes = map[string]Theme {
+	"default": {
+		Text:       termbox.ColorDefault,
+		Keyword:    termbox.ColorBlue,
+		Type:       termbox.ColorCyan,
+		Function:   termbox.Attribute(115),
+		String:     termbox.Attribute(107),
+		Number:     termbox.Attribute(209),
+		Identifier: termbox.Attribute(180),
+		Comment:    termbox.Attribute(244),
+		Background: termbox.ColorBlack,
+		Statement:  termbox.Attribute(237),
+		RowBg:      termbox.Attribute(0),
+		RowBgAlt:   termbox.Attribute(236),
+		SelectedBg: termbox.Attribute(22),
+		Error:      termbox.ColorRed,
+		Warning:    termbox.Attribute(137),
+		StatusText: termbox.ColorDefault,
+		StatusBg:   termbox.ColorDefault,
+	},
+	"light": {
+		Text:       termbox.ColorBlack,
+		Keyword:    termbox.Attribute(25),
+		Type:       termbox.Attribute(31),
+		Function:   termbox.Attribute(92),
+		String:     termbox.Attribute(29),
+		Number:     termbox.Attribute(167),
+		Identifier: termbox.Attribute(95),
+		Comment:    termbox.Attribute(246),
+		Background: termbox.Attribute(232),
+		Statement:  termbox.Attribute(255),
+		RowBg:      termbox.Attribute(232),
+		RowBgAlt:   termbox.Attribute(256),
+		SelectedBg: termbox.Attribute(195),
+		Error:      termbox.Attribute(218),
+		Warning:    termbox.Attribute(224),
+		StatusText: termbox.ColorBlack,
+		StatusBg:   termbox.Attribute(253),
+	},
+	"solarized": {
+		Text:       termbox.Attribute(245),
+		Keyword:    termbox.Attribute(34),
+		Type:       termbox.Attribute(137),
+		Function:   termbox.Attribute(38),
+		String:     termbox.Attribute(38),
+		Number:     termbox.Attribute(167),
+		Identifier: termbox.Attribute(126),
+		Comment:    termbox.Attribute(241),
+		Background: termbox.Attribute(235),
+		Statement:  termbox.Attribute(236),
+		RowBg:      termbox.Attribute(235),
+		RowBgAlt:   termbox.Attribute(236),
+		SelectedBg: termbox.Attribute(24),
+		Error:      termbox.Attribute(161),
+		Warning:    termbox.Attribute(137),
+		StatusText: termbox.Attribute(246),
+		StatusBg:   termbox.Attribute(236),
+	},
+	// Only strong colors, with plain backgrounds apart from the statement
+	// and the selection.
+	"high-contrast": {
+		Text:       termbox.Attribute(232),
+		Keyword:    termbox.Attribute(227),
+		Type:       termbox.Attribute(88),
+		Function:   termbox.Attribute(121),
+		String:     termbox.Attribute(214),
+		Number:     termbox.Attribute(215),
+		Identifier: termbox.Attribute(230),
+		Comment:    termbox.Attribute(189),
+		Background: termbox.Attribute(17),
+		Statement:  termbox.Attribute(26),
+		RowBg:      termbox.Attribute(17),
+		RowBgAlt:   termbox.Attribute(17),
+		SelectedBg: termbox.Attribute(23),
+		Error:      termbox.Attribute(161),
+		Warning:    termbox.Attribute(131),
+		StatusText: termbox.Attribute(17),
+		StatusBg:   termbox.Attribute(232),
+	},
+}
+
+func init() {
+	themes["dark"] = themes["default"]
+}
+
+var namedColors = map[string]termbox.Attribute {
+	"default": termbox.ColorDefault,
+	"black":   termbox.ColorBlack,
+	"red":     termbox.ColorRed,
+	"green":   termbox.ColorGreen,
+	"yellow":  termbox.ColorYellow,
+	"blue":    termbox.ColorBlue,
+	"magenta": termbox.ColorMagenta,
+	"cyan":    termbox.ColorCyan,
+	"white":   termbox.ColorWhite,
+}
+
+// Colors are either one of the names above, an index into the 256-color
+// palette or #rrggbb.
+func parseColor(name string) (termbox.Attribute, error) {
+	if color, ok := namedColors[name]; ok {
+		return color, nil
+	}
+
+	if strings.HasPrefix(name, "#") {
+		return parseHexColor(name)
+	}
+
+	index, err := strconv.Atoi(name)
+	if err != nil || index < 0 || index > 255 {
+		return 0, errors.New(trf("Invalid color '%s'", name))
+	}
+
+	return termbox.Attribute(index + 1), nil
+}
+
+func (t *Theme) color(name string) (*termbox.Attribute, error) {
+	switch name {
+	case "text":
+		return &t.Text, nil
+	case "keyword":
+		return &t.Keyword, nil
+	case "type":
+		return &t.Type, nil
+	case "function":
+		return &t.Function, nil
+	case "string":
+		return &t.String, nil
+	case "number":
+		return &t.Number, nil
+	case "identifier":
+		return &t.Identifier, nil
+	case "comment":
+		return &t.Comment, nil
+	case "background":
+		return &t.Background, nil
+	case "statement":
+		return &t.Statement, nil
+	case "row":
+		return &t.RowBg, nil
+	case "row_alt":
+		return &t.RowBgAlt, nil
+	case "selected":
+		return &t.SelectedBg, nil
+	case "error":
+		return &t.Error, nil
+	case "warning":
+		return &t.Warning, nil
+	case "status":
+		return &t.StatusText, nil
+	case "status_background":
+		return &t.StatusBg, nil
+	}
+
+	return nil, errors.New(trf("Unknown theme color '%s'", name))
+}
+
+func (t *Theme) override(colors map[string]string) error {
+	for key, value := range colors {
+		target, err := t.color(key)
+		if err != nil {
+			return err
+		}
+
+		*target, err = parseColor(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Themes that aren't built in are read from a JSON file: either the path
+// given, or <name>.json in the themes directory next to the autosaves.
+func readThemeFile(name string) (Theme, error) {
+	path := name
+	if !strings.HasSuffix(name, ".json") {
+		path = filepath.Join(configDir(), "themes", name + ".json")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Theme {}, errors.New(trf("Unknown theme '%s'", name))
+	}
+
+	var file themeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Theme {}, errors.New(trf("Invalid theme file %s: %s",
+						path, err))
+	}
+
+	if file.Base == "" {
+		file.Base = defaultThemeName
+	}
+
+	theme, ok := themes[file.Base]
+	if !ok {
+		return theme, errors.New(trf("Unknown base theme '%s' in %s",
+					     file.Base, path))
+	}
+
+	err = theme.override(file.Colors)
+	return theme, err
+}
+
+func loadTheme(name string, colors map[string]string) (Theme, error) {
+	if name == "" {
+		name = defaultThemeName
+	}
+
+	theme, ok := themes[name]
+	if !ok {
+		var err error
+		if theme, err = readThemeFile(name); err != nil {
+			return theme, err
+		}
+	}
+
+	err := theme.override(colors)
+	return theme, err
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"time"
+	"errors"
+	"strings"
+	"database/sql"
+)
+
+const datetimeLayout string = "2006-01-02 15:04:05"
+
+// How DATE, DATETIME and TIMESTAMP values are shown in the grid: converted
+// from the server's time zone to another (unless to is nil, or for dates)
+// and written with a strftime format.
+type timeDisplay struct {
+	from   *time.Location
+	to     *time.Location
+	format string
+}
+
+func init() {
+	registerCommand(command {
+		name:  "timezone",
+		usage: "[zone|off]",
+		help:  "Show dates and times in another time zone",
+		run:   timezoneCommand,
+	})
+}
+
+func dbTimezone() string {
+	if config.DBTimezone == "" {
+		return "UTC"
+	}
+
+	return config.DBTimezone
+}
+
+func checkTimezones(c Config) error {
+	for _, zone := range []string {c.Timezone, c.DBTimezone} {
+		if _, err := time.LoadLocation(zone); err != nil {
+			return errors.New(trf("Unknown time zone '%s'", zone))
+		}
+	}
+
+	return nil
+}
+
+// Returns nil when values are shown as the server sends them.
+func displayTimes() *timeDisplay {
+	convert := config.Timezone != "" && config.Timezone != dbTimezone()
+	if !convert && config.DateFormat == "" {
+		return nil
+	}
+
+	from, err := time.LoadLocation(dbTimezone())
+	if err != nil {
+		return nil
+	}
+
+	d := &timeDisplay {
+		from:   from,
+		format: config.DateFormat,
+	}
+
+	if convert {
+		if d.to, err = time.LoadLocation(config.Timezone); err != nil {
+			return nil
+		}
+	}
+
+	return d
+}
+
+// Scans a column into a NullString, writing the time.Time values the driver
+// returns with parse_time on the way MySQL prints them rather than in
+// RFC 3339.
+type textValue struct {
+	target *sql.NullString
+	kind   string
+}
+
+func columnKinds(types []*sql.ColumnType) []string {
+	kinds := make([]string, len(types))
+	for i, t := range types {
+		kinds[i] = t.DatabaseTypeName()
+	}
+
+	return kinds
+}
+
+// Returns a scanner for each column, each filling in one of values.
+func textScanners(kinds []string, values []sql.NullString) []interface{} {
+	scanners := make([]interface{}, len(values))
+	for i := range values {
+		scanners[i] = textValue {&values[i], kinds[i]}
+	}
+
+	return scanners
+}
+
+func (v textValue) Scan(src interface{}) error {
+	if t, ok := src.(time.Time); ok {
+		v.target.String = formatTime(t, v.kind)
+		v.target.Valid = true
+		return nil
+	}
+
+	return v.target.Scan(src)
+}
+
+// Fractional seconds are only shown when there are some. The driver returns
+// MySQL's zero dates as time.Time's zero value, which is written back as
+// the zero date so dumps restore it; MySQL's dates start at year 1000, so
+// nothing real is mistaken for one.
+func formatTime(t time.Time, kind string) string {
+	if t.IsZero() {
+		if kind == "DATE" {
+			return "0000-00-00"
+		}
+
+		return "0000-00-00 00:00:00"
+	}
+
+	if kind == "DATE" {
+		return t.Format("2006-01-02")
+	}
+
+	return t.Format(datetimeLayout + ".999999")
+}
+
+func isTemporal(kind string) bool {
+	return kind == "DATE" || kind == "DATETIME" || kind == "TIMESTAMP"
+}
+
+// Without a format, keeps the value's fractional seconds. Zero dates and
+// anything else that doesn't parse are left alone.
+func (d *timeDisplay) convert(value, kind string) (string, bool) {
+	layout := datetimeLayout
+	if kind == "DATE" {
+		layout = "2006-01-02"
+	}
+
+	t, err := time.ParseInLocation(layout, value, d.from)
+	if err != nil {
+		return value, false
+	}
+
+	if d.to != nil && kind != "DATE" {
+		t = t.In(d.to)
+	}
+
+	if d.format != "" && kind == "DATE" {
+		return strftime(t, dateOnly(d.format)), true
+	}
+
+	if d.format != "" {
+		return strftime(t, d.format), true
+	}
+
+	if dot := strings.IndexByte(value, '.'); dot >= 0 {
+		layout += "." + strings.Repeat("0", len(value) - dot - 1)
+	}
+
+	formatted := t.Format(layout)
+	return formatted, formatted != value
+}
+
+func timezoneCommand(args []string) error {
+	switch {
+	case len(args) > 1:
+		return usageError("timezone")
+
+	case len(args) == 0:
+		if config.Timezone == "" {
+			showMessage(trf("Dates and times are shown as stored (%s)",
+					dbTimezone()))
+		} else {
+			showMessage(trf("Dates and times are shown in %s",
+					config.Timezone))
+		}
+		return nil
+
+	case args[0] == "off":
+		config.Timezone = ""
+
+	default:
+		if _, err := time.LoadLocation(args[0]); err != nil {
+			return errors.New(trf("Unknown time zone '%s'", args[0]))
+		}
+
+		config.Timezone = args[0]
+	}
+
+	showMessage(tr("The new time zone applies from the next query"))
+	return nil
+}
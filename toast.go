@@ -0,0 +1,104 @@
+package main
+
+import (
+	"time"
+	"errors"
+	"database/sql/driver"
+	"github.com/go-sql-driver/mysql"
+	"github.com/briansteffens/tui"
+)
+
+const toastDuration = 4 * time.Second
+
+// A notification drawn over the top right corner for a few seconds, for
+// things that finish in the background so they don't replace whatever is in
+// the status bar.
+var toast        tui.Label
+var toastVisible bool
+
+// Counts toasts shown, so hiding an old one doesn't hide a newer one.
+var toastCount int
+
+func showToast(text string, isError bool) {
+	if isError {
+		recordError("", errors.New(text))
+	} else {
+		recordMessage(text, false)
+	}
+
+	displayToast(text, isError)
+}
+
+func displayToast(text string, isError bool) {
+	toast = tui.Label {
+		Text: " " + text + " ",
+		Fg:   theme.Text,
+		Bg:   theme.SelectedBg,
+	}
+
+	if isError {
+		toast.Bg = theme.Error
+	}
+
+	toastCount++
+	shown := toastCount
+
+	if !toastVisible {
+		toastVisible = true
+		updateControls()
+	}
+	resizeToast()
+
+	time.AfterFunc(toastDuration, func() {
+		post(func() {
+			if toastCount == shown {
+				hideToast()
+			}
+		})
+	})
+}
+
+func hideToast() {
+	toastVisible = false
+	updateControls()
+}
+
+func resizeToast() {
+	width := displayWidth(toast.Text)
+	if width > container.Width - 2 {
+		width = container.Width - 2
+	}
+
+	toast.Bounds.Top = 0
+	toast.Bounds.Left = container.Width - width - 1
+	toast.Bounds.Width = width
+	toast.Bounds.Height = 1
+}
+
+// The last progress shown by a background job.
+var progressText string
+
+func showProgress(text string) {
+	status.Text = text
+	progressText = text
+}
+
+// Background jobs show their progress in the status bar, which is cleared
+// when they finish unless something else has been shown there since.
+func clearProgress() {
+	if status.Text == progressText {
+		status.Text = ""
+	}
+
+	progressText = ""
+}
+
+// Whether a background job (an export, import, dump or script) is running.
+func jobRunning() bool {
+	return progressText != ""
+}
+
+func connectionLost(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) ||
+	       errors.Is(err, mysql.ErrInvalidConn)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+	"encoding/json"
+)
+
+// The results as tools receive them.
+type resultsJSON struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+func currentResultsJSON() ([]byte, error) {
+	out := resultsJSON {
+		Columns: []string {},
+		Rows:    rawResultRows(),
+	}
+
+	for i := range results.Columns {
+		out.Columns = append(out.Columns, columnName(i))
+	}
+
+	return json.Marshal(out)
+}
+
+// Replaces what was the statement when the tool started, unless the
+// editor has changed under it since.
+func replaceStatement(s Statement, query, text string) error {
+	chars := []rune(editor.GetText())
+
+	end := s.start + s.length
+	if end > len(chars) || string(chars[s.start:end]) != query {
+		return errors.New(
+			tr("The statement changed while the tool ran"))
+	}
+
+	setEditorText(string(chars[:s.start]) + text + string(chars[end:]),
+		      s.start)
+	editor.SetCursor(s.start)
+	return nil
+}
+
+// The tool runs in the background, stopped if prequel exits, and its output
+// is used once it finishes.
+func runTool(name string, tool Tool, args []string) error {
+	if len(tool.Command) == 0 {
+		return errors.New(trf("The %s tool has no command", name))
+	}
+
+	switch tool.Output {
+	case "", "popup", "editor", "replace":
+	default:
+		return errors.New(trf("Unknown tool output '%s'", tool.Output))
+	}
+
+	refreshHighlight()
+
+	query := statementQuery(doc.text, statement)
+	target := statement
+
+	var stdin []byte
+
+	switch tool.Input {
+	case "", "statement":
+		stdin = []byte(query)
+	case "results":
+		var err error
+		if stdin, err = currentResultsJSON(); err != nil {
+			return err
+		}
+	default:
+		return errors.New(trf("Unknown tool input '%s'", tool.Input))
+	}
+
+	argv := append(append([]string {}, tool.Command...), args...)
+	status.Text = trf("Running %s...", name)
+
+	go func() {
+		defer recoverCrash()
+
+		var stdout, stderr bytes.Buffer
+
+		cmd := exec.CommandContext(appContext, argv[0], argv[1:]...)
+		cmd.Stdin = bytes.NewReader(stdin)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+
+		post(func() {
+			status.Text = ""
+
+			if err != nil {
+				message := strings.TrimSpace(stderr.String())
+				if message == "" {
+					message = err.Error()
+				}
+
+				showError(name + ": " + message)
+				return
+			}
+
+			output := stdout.String()
+
+			switch tool.Output {
+			case "", "popup":
+				showPopup(name, output, false)
+			case "editor":
+				insertAtCursor(output)
+			case "replace":
+				err = replaceStatement(target, query, output)
+			}
+
+			if err != nil {
+				showError(err.Error())
+			}
+		})
+	}()
+
+	return nil
+}
+
+func registerTools(tools map[string]Tool) {
+	for name, tool := range tools {
+		name, tool := name, tool
+
+		help := tool.Help
+		if help == "" {
+			help = "Run " + strings.Join(tool.Command, " ")
+		}
+
+		registerUserCommand(command {
+			name:  name,
+			usage: "[args]",
+			help:  help,
+			run: func(args []string) error {
+				return runTool(name, tool, args)
+			},
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"database/sql"
+)
+
+// Statements from the editor all run on one connection, so USE, SET and
+// transactions carry over from one statement to the next.
+var editorConn *sql.Conn
+
+// The server's id for editorConn, to stop a statement on it from another
+// connection. Empty if unknown, as with SQLite.
+var editorConnID string
+
+// Set while an export reads from editorConn in the background, since
+// nothing else can use the connection until it's done.
+var editorConnBusy bool
+
+// Whether a BEGIN or START TRANSACTION has been run in the editor without a
+// COMMIT or ROLLBACK since. Statements that commit implicitly (like DDL)
+// aren't noticed.
+var inTransaction bool
+
+func editorConnection() (*sql.Conn, error) {
+	if editorConnBusy {
+		return nil, errors.New(tr("Wait for the export to finish"))
+	}
+
+	if editorConn == nil {
+		conn, err := db.Conn(appContext)
+		if err != nil {
+			return nil, err
+		}
+
+		editorConn = conn
+		editorConnID = connectionID(conn)
+	}
+
+	return editorConn, nil
+}
+
+func connectionID(conn *sql.Conn) string {
+	query := ""
+	switch sqlDialect {
+	case dialectMySQL:
+		query = "SELECT CONNECTION_ID()"
+	case dialectPostgres:
+		query = "SELECT pg_backend_pid()"
+	default:
+		return ""
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	id := ""
+	if err := conn.QueryRowContext(ctx, query).Scan(&id); err != nil {
+		return ""
+	}
+
+	return id
+}
+
+// Stops the statement running on the editor's connection without closing
+// it, as cancelling its context would, so the session's transaction and
+// default database survive. Returns false if it couldn't be.
+func stopEditorStatement() bool {
+	statement := ""
+	switch {
+	case editorConnID == "":
+		return false
+	case sqlDialect == dialectMySQL:
+		statement = "KILL QUERY " + editorConnID
+	case sqlDialect == dialectPostgres:
+		statement = "SELECT pg_cancel_backend(" + editorConnID + ")"
+	default:
+		return false
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, statement)
+	return err == nil
+}
+
+// Drops the editor's connection, and with it any open transaction, so the
+// next statement starts on a fresh one.
+func resetEditorConnection() {
+	if editorConn != nil {
+		editorConn.Close()
+		editorConn = nil
+		editorConnID = ""
+	}
+
+	inTransaction = false
+}
+
+// The first two words of a statement, upper-cased, skipping comments. A
+// word that isn't there (as when a symbol comes first) is "".
+func leadingWords(query string) []string {
+	text := []rune(query)
+	words := []string {}
+
+	for _, t := range lex(text, sqlDialect) {
+		if len(words) == 2 {
+			break
+		}
+
+		switch t.kind {
+		case tokenWhitespace, tokenComment:
+			continue
+		case tokenWord:
+			word := string(text[t.start:t.end])
+			words = append(words, strings.ToUpper(word))
+		default:
+			return append(words, "", "")[:2]
+		}
+	}
+
+	return append(words, "", "")[:2]
+}
+
+// Called after each statement from the editor runs successfully.
+func trackTransaction(query string) {
+	words := leadingWords(query)
+
+	switch {
+	case words[0] == "BEGIN",
+	     words[0] == "START" && words[1] == "TRANSACTION":
+		inTransaction = true
+	case words[0] == "COMMIT",
+	     words[0] == "ROLLBACK" && words[1] != "TO":
+		inTransaction = false
+	}
+}
+
+// Runs COMMIT or ROLLBACK to end the open transaction.
+func endTransaction(statement string) error {
+	conn, err := editorConnection()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := queryContext()
+	defer cancel()
+
+	if _, err := execStatement(ctx, conn, statement); err != nil {
+		return err
+	}
+
+	inTransaction = false
+	return nil
+}
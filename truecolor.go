@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"github.com/nsf/termbox-go"
+)
+
+// Set when drawing with 24-bit color escape sequences instead of the
+// 256-color palette.
+var truecolor bool
+
+var themeColorNames = []string {
+	"text", "keyword", "type", "function", "string", "number",
+	"identifier", "comment", "background", "statement", "row", "row_alt",
+	"selected", "error", "warning", "status", "status_background",
+}
+
+// The first 16 colors are whatever the terminal's scheme makes them, so
+// these are only xterm's defaults.
+var systemColors = [16][3]uint8 {
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00},
+	{0xcd, 0xcd, 0x00}, {0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd},
+	{0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5}, {0x7f, 0x7f, 0x7f},
+	{0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff},
+	{0xff, 0xff, 0xff},
+}
+
+var cubeLevels = [6]uint8 {0, 95, 135, 175, 215, 255}
+
+// Uses the truecolor setting if there is one, otherwise trusts COLORTERM,
+// which terminals that support 24-bit color set.
+func useTruecolor(setting *bool) bool {
+	if setting != nil {
+		return *setting
+	}
+
+	colorterm := os.Getenv("COLORTERM")
+	return colorterm == "truecolor" || colorterm == "24bit"
+}
+
+func isRGB(a termbox.Attribute) bool {
+	return a >= termbox.RGBToAttribute(0, 0, 0)
+}
+
+// Colors are written as #rrggbb.
+func parseHexColor(name string) (termbox.Attribute, error) {
+	value, err := strconv.ParseUint(name[1:], 16, 32)
+	if err != nil || len(name) != 7 {
+		return 0, errors.New(trf("Invalid color '%s'", name))
+	}
+
+	return termbox.RGBToAttribute(uint8(value >> 16), uint8(value >> 8),
+				      uint8(value)), nil
+}
+
+func paletteRGB(index int) (uint8, uint8, uint8) {
+	switch {
+	case index < 16:
+		c := systemColors[index]
+		return c[0], c[1], c[2]
+	case index < 232:
+		index -= 16
+		return cubeLevels[index / 36], cubeLevels[index / 6 % 6],
+		       cubeLevels[index % 6]
+	}
+
+	gray := uint8(8 + (index - 232) * 10)
+	return gray, gray, gray
+}
+
+// Picks the closest color from the cube and the grays, skipping the system
+// colors since the terminal may have changed them.
+func nearestPaletteIndex(r, g, b uint8) int {
+	best := 16
+	bestDistance := -1
+
+	for index := 16; index < 256; index++ {
+		pr, pg, pb := paletteRGB(index)
+		dr := int(pr) - int(r)
+		dg := int(pg) - int(g)
+		db := int(pb) - int(b)
+
+		distance := dr * dr + dg * dg + db * db
+		if bestDistance < 0 || distance < bestDistance {
+			best = index
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
+
+// Converts a color to what the output mode can draw: in RGB mode palette
+// colors are looked up, otherwise #rrggbb colors are approximated.
+func outputColor(a termbox.Attribute, rgb bool) termbox.Attribute {
+	if a == termbox.ColorDefault || isRGB(a) == rgb {
+		return a
+	}
+
+	if rgb {
+		return termbox.RGBToAttribute(paletteRGB(int(a) - 1))
+	}
+
+	index := nearestPaletteIndex(termbox.AttributeToRGB(a))
+	return termbox.Attribute(index + 1)
+}
+
+func (t Theme) forOutput(rgb bool) Theme {
+	for _, name := range themeColorNames {
+		color, _ := t.color(name)
+		*color = outputColor(*color, rgb)
+	}
+
+	return t
+}
+
+func enableTruecolor() {
+	if truecolor {
+		termbox.SetOutputMode(termbox.OutputRGB)
+	}
+}
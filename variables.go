@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"errors"
+	"regexp"
+	"strconv"
+	"github.com/nsf/termbox-go"
+	"github.com/briansteffens/escapebox"
+)
+
+var variableName = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+// The filter of the last variables listing, so it can be refreshed after a
+// change.
+var variablesFilter string
+
+func init() {
+	registerCommand(command {
+		name:  "variables",
+		usage: "[pattern]",
+		help:  "List global variables, * matches anything",
+		run:   variablesCommand,
+	})
+
+	registerCommand(command {
+		name:  "setvar",
+		usage: "<name> <value>",
+		help:  "Change a global variable",
+		run:   setVariableCommand,
+	})
+}
+
+func showVariables(filter string) error {
+	query := "SHOW GLOBAL VARIABLES"
+	args := []interface{} {}
+
+	if filter != "" {
+		query += " LIKE ?"
+		args = append(args, likePattern(filter))
+	}
+
+	columns, rows, err := fetchStrings(query, args...)
+	if err != nil {
+		return err
+	}
+
+	showResults(columns, rows)
+	resultsView = "variables"
+	variablesFilter = filter
+
+	status.Text = trf("%d variables  (s: change the selected one)",
+			  len(rows))
+	return nil
+}
+
+func variablesCommand(args []string) error {
+	switch len(args) {
+	case 0:
+		return showVariables("")
+	case 1:
+		return showVariables(args[0])
+	}
+
+	return usageError("variables")
+}
+
+// Numbers are passed as they are, since numeric variables reject strings.
+// Anything else (ON, a path, a mode list) is quoted.
+func variableValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+
+	return quoteString(value)
+}
+
+func setGlobal(name, value string) {
+	statement := fmt.Sprintf("SET GLOBAL %s = %s", name,
+				 variableValue(value))
+
+	confirm(statement + "?", func() {
+		ctx, cancel := queryContext()
+		defer cancel()
+
+		if _, err := execStatement(ctx, db, statement); err != nil {
+			showError(err.Error())
+			return
+		}
+
+		if resultsView == "variables" {
+			showVariables(variablesFilter)
+		}
+
+		showMessage(trf("Set %s to %s", name, value))
+	})
+}
+
+func setVariableCommand(args []string) error {
+	if len(args) != 2 {
+		return usageError("setvar")
+	}
+
+	if !variableName.MatchString(args[0]) {
+		return errors.New(trf("Invalid variable name '%s'", args[0]))
+	}
+
+	setGlobal(args[0], args[1])
+	return nil
+}
+
+func handleVariablesEvent(ev escapebox.Event) bool {
+	if resultsView != "variables" || ev.Type != termbox.EventKey ||
+	   ev.Ch != 's' || results.SelectedRow >= len(results.Rows) {
+		return false
+	}
+
+	name := cellValue(results.SelectedRow, 0)
+
+	askFor(name + " = ", func(value string) {
+		setGlobal(name, value)
+	})
+
+	return true
+}